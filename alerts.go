@@ -0,0 +1,299 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"gator/internal/database"
+	"gator/internal/markup"
+	"log"
+	"math"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Kinds of alert rule, by what the pattern is matched against.
+const (
+	// whole words or phrases in the title and text, ignoring case
+	alertKeyword = "keyword"
+	// a Go regular expression over the title and text
+	alertRegex = "regex"
+	// part of the author's name, ignoring case
+	alertAuthor = "author"
+)
+
+// alertHookTimeout bounds how long alert_command may run.
+const alertHookTimeout = 10 * time.Second
+
+// compileAlertRule turns a rule's pattern into the regexp it is matched
+// with.
+func compileAlertRule(kind, pattern string) (*regexp.Regexp, error) {
+	if strings.TrimSpace(pattern) == "" {
+		return nil, errors.New("alert pattern must not be empty")
+	}
+	switch kind {
+	case alertKeyword:
+		// \b would not work for keywords that start or end with
+		// punctuation, like "c++"
+		return regexp.Compile(`(?i)(?:^|[^\pL\pN_])` + regexp.QuoteMeta(pattern) + `(?:$|[^\pL\pN_])`)
+	case alertRegex:
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex: %w", err)
+		}
+		return re, nil
+	case alertAuthor:
+		return regexp.Compile(`(?i)` + regexp.QuoteMeta(pattern))
+	}
+	return nil, fmt.Errorf("alert kind must be keyword, regex or author, not %q", kind)
+}
+
+// alertText is what keyword and regex rules are matched against: the title
+// and the plain text of the description and content.
+func alertText(p database.Post) string {
+	return strings.Join([]string{
+		p.Title,
+		markup.Excerpt(p.Description.String, math.MaxInt),
+		markup.Excerpt(p.Content.String, math.MaxInt),
+	}, "\n")
+}
+
+// checkAlerts matches new posts in feed against the alert rules that apply
+// to it, records the matches and announces those of rules with notify set.
+func checkAlerts(s *state, feed database.Feed, posts []database.Post) {
+	if len(posts) == 0 {
+		return
+	}
+	ctx := context.Background()
+	rules, err := s.db.GetAlertRulesForFeed(ctx, feed.ID)
+	if err != nil {
+		log.Printf("error loading alert rules (url=%s): %v", feed.Url, err)
+		return
+	}
+	if len(rules) == 0 {
+		return
+	}
+
+	patterns := make([]*regexp.Regexp, len(rules))
+	for i, r := range rules {
+		// addalert checks patterns, so this shouldn't fail
+		if patterns[i], err = compileAlertRule(r.Kind, r.Pattern); err != nil {
+			log.Printf("error compiling alert rule %s: %v", r.ID, err)
+		}
+	}
+
+	for _, p := range posts {
+		text := alertText(p)
+		for i, r := range rules {
+			re := patterns[i]
+			if re == nil {
+				continue
+			}
+			if r.Kind == alertAuthor {
+				if !re.MatchString(p.Author.String) {
+					continue
+				}
+			} else if !re.MatchString(text) {
+				continue
+			}
+
+			n, err := s.db.CreateAlert(ctx, database.CreateAlertParams{
+				ID:        uuid.New(),
+				CreatedAt: time.Now(),
+				RuleID:    r.ID,
+				PostID:    p.ID,
+			})
+			if err != nil {
+				log.Printf("error recording alert (rule=%s, url=%s): %v", r.ID, p.Url, err)
+				continue
+			}
+			if n == 1 && r.Notify {
+				notifyAlert(s, r, feed, p)
+			}
+		}
+	}
+}
+
+// notifyAlert prints a match and passes it to alert_command, if one is
+// configured, in GATOR_ALERT_* environment variables.
+func notifyAlert(s *state, r database.GetAlertRulesForFeedRow, feed database.Feed, p database.Post) {
+	fmt.Printf("alert for %s: %s %q matched %q in %s\n  %s\n", r.UserName, r.Kind, r.Pattern, p.Title, feed.Name, p.Url)
+
+	if s.cfg.AlertCommand == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), alertHookTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "sh", "-c", s.cfg.AlertCommand)
+	cmd.Env = append(os.Environ(),
+		"GATOR_ALERT_USER="+r.UserName,
+		"GATOR_ALERT_KIND="+r.Kind,
+		"GATOR_ALERT_PATTERN="+r.Pattern,
+		"GATOR_ALERT_TITLE="+p.Title,
+		"GATOR_ALERT_URL="+p.Url,
+		"GATOR_ALERT_AUTHOR="+p.Author.String,
+		"GATOR_ALERT_FEED="+feed.Name,
+	)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		log.Printf("error running alert_command (rule=%s): %v", r.ID, err)
+	}
+}
+
+func handlerAddAlert(s *state, cmd command, user database.User) error {
+	fs := newFlagSet("addalert")
+	feedURL := fs.String("feed", "", "only match posts from this feed")
+	notify := fs.Bool("notify", false, "announce matches as agg finds them")
+	args, err := parseArgs(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 2 {
+		return errors.New("addalert requires a kind (keyword, regex or author) and a pattern")
+	}
+	kind, pattern := args[0], args[1]
+	if _, err := compileAlertRule(kind, pattern); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	var feedID uuid.NullUUID
+	if *feedURL != "" {
+		feed, err := s.db.GetFeedByURL(ctx, *feedURL)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("feed not found: %s", *feedURL)
+			}
+			return err
+		}
+		feedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+	}
+
+	rule, err := s.db.CreateAlertRule(ctx, database.CreateAlertRuleParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UserID:    user.ID,
+		FeedID:    feedID,
+		Kind:      kind,
+		Pattern:   pattern,
+		Notify:    *notify,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("created alert rule %s\n", rule.ID)
+	if !feedID.Valid {
+		fmt.Println("new posts in every feed you follow will be checked")
+	}
+	return nil
+}
+
+func handlerAlertRules(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 0 {
+		return errors.New("alertrules takes no arguments")
+	}
+
+	rules, err := s.db.GetAlertRulesForUser(context.Background(), user.ID)
+	if err != nil {
+		return err
+	}
+
+	l := newListing("id", "kind", "pattern", "feed_id", "feed_name", "notify", "matches", "created_at")
+	for _, r := range rules {
+		l.add(r.ID, r.Kind, r.Pattern, r.FeedID, r.FeedName, r.Notify, r.Matches, r.CreatedAt)
+	}
+	return render(s, l, func() {
+		for _, r := range rules {
+			fmt.Printf("* %s %q\n", r.Kind, r.Pattern)
+			fmt.Printf("  id: %s\n", r.ID)
+			if r.FeedName.Valid {
+				fmt.Printf("  feed: %s\n", r.FeedName.String)
+			} else {
+				fmt.Println("  feed: every feed you follow")
+			}
+			if r.Notify {
+				fmt.Println("  notify: yes")
+			}
+			fmt.Printf("  matches: %d\n", r.Matches)
+		}
+	})
+}
+
+func handlerRemoveAlert(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 1 {
+		return errors.New("removealert requires an alert rule id")
+	}
+	id, err := uuid.Parse(cmd.args[0])
+	if err != nil {
+		return fmt.Errorf("invalid alert rule id: %s", cmd.args[0])
+	}
+
+	n, err := s.db.DeleteAlertRule(context.Background(), database.DeleteAlertRuleParams{
+		ID:     id,
+		UserID: user.ID,
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("alert rule not found: %s", id)
+	}
+	fmt.Printf("removed alert rule %s\n", id)
+	return nil
+}
+
+func handlerAlerts(s *state, cmd command, user database.User) error {
+	fs := newFlagSet("alerts")
+	ruleFlag := fs.String("rule", "", "only show matches of this rule id")
+	limit := fs.Int("limit", 20, "number of matches to show")
+	args, err := parseArgs(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return errors.New("alerts takes no arguments")
+	}
+	if *limit <= 0 {
+		return errors.New("alerts --limit must be a positive integer")
+	}
+
+	var ruleID uuid.NullUUID
+	if *ruleFlag != "" {
+		id, err := uuid.Parse(*ruleFlag)
+		if err != nil {
+			return fmt.Errorf("invalid alert rule id: %s", *ruleFlag)
+		}
+		ruleID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+
+	alerts, err := s.db.GetAlertsForUser(context.Background(), database.GetAlertsForUserParams{
+		UserID: user.ID,
+		RuleID: ruleID,
+		Limit:  int32(*limit),
+	})
+	if err != nil {
+		return err
+	}
+
+	l := newListing("id", "rule_id", "kind", "pattern", "post_id", "title", "url", "author", "feed_name", "published_at", "created_at")
+	for _, a := range alerts {
+		l.add(a.ID, a.RuleID, a.Kind, a.Pattern, a.PostID, a.Title, a.Url, a.Author, a.FeedName, a.PublishedAt, a.CreatedAt)
+	}
+	return render(s, l, func() {
+		for _, a := range alerts {
+			fmt.Printf("* %s  %s\n", fromTimestamp(a.CreatedAt).Format("Mon Jan 2 15:04"), a.Title)
+			fmt.Printf("  %s %q in %s\n", a.Kind, a.Pattern, a.FeedName)
+			if a.Author.Valid {
+				fmt.Printf("  by %s\n", a.Author.String)
+			}
+			fmt.Printf("  %s\n", a.Url)
+		}
+	})
+}
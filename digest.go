@@ -0,0 +1,343 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"gator/internal/config"
+	"gator/internal/database"
+	"gator/internal/markup"
+	"html/template"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+//go:embed mail
+var mailFiles embed.FS
+
+var (
+	digestText = texttemplate.Must(texttemplate.ParseFS(mailFiles, "mail/digest.txt"))
+	digestHTML = template.Must(template.ParseFS(mailFiles, "mail/digest.html"))
+)
+
+const (
+	digestSummaryLength = 240
+	// digestFrom is the sender of .eml files when smtp_from isn't set.
+	digestFrom = "gator@localhost"
+)
+
+// digest is what the digest templates render: the new posts for one user,
+// grouped by feed.
+type digest struct {
+	User    string
+	Subject string
+	Since   string
+	Count   int
+	Feeds   []digestFeed
+}
+
+type digestFeed struct {
+	Name  string
+	URL   string
+	Posts []digestPost
+}
+
+type digestPost struct {
+	Title   string
+	URL     string
+	Date    string
+	Summary string
+}
+
+// buildDigest groups posts, which GetDigestPosts returns ordered by feed.
+func buildDigest(user database.User, since time.Time, posts []database.GetDigestPostsRow) digest {
+	d := digest{
+		User:  user.Name,
+		Since: since.Format("Mon Jan 2 15:04"),
+		Count: len(posts),
+	}
+	for _, p := range posts {
+		if len(d.Feeds) == 0 || d.Feeds[len(d.Feeds)-1].URL != p.FeedUrl {
+			d.Feeds = append(d.Feeds, digestFeed{Name: p.FeedName, URL: p.FeedUrl})
+		}
+		date := p.CreatedAt
+		if p.PublishedAt.Valid {
+			date = p.PublishedAt.Time
+		}
+		feed := &d.Feeds[len(d.Feeds)-1]
+		feed.Posts = append(feed.Posts, digestPost{
+			Title:   p.Title,
+			URL:     p.Url,
+			Date:    fromTimestamp(date).Format("Mon Jan 2 15:04"),
+			Summary: markup.Excerpt(p.Description.String, digestSummaryLength),
+		})
+	}
+
+	postNoun, feedNoun := "posts", "feeds"
+	if d.Count == 1 {
+		postNoun = "post"
+	}
+	if len(d.Feeds) == 1 {
+		feedNoun = "feed"
+	}
+	d.Subject = fmt.Sprintf("gator: %d new %s from %d %s", d.Count, postNoun, len(d.Feeds), feedNoun)
+	return d
+}
+
+// message renders d as a MIME email with a text and an HTML body.
+func (d digest) message(from, to string, date time.Time) ([]byte, error) {
+	var text, html bytes.Buffer
+	if err := digestText.Execute(&text, d); err != nil {
+		return nil, err
+	}
+	if err := digestHTML.Execute(&html, d); err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, part := range []struct {
+		contentType string
+		content     []byte
+	}{
+		{"text/plain; charset=utf-8", text.Bytes()},
+		{"text/html; charset=utf-8", html.Bytes()},
+	} {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write(part.content); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	domain := "localhost"
+	if _, host, ok := strings.Cut(from, "@"); ok {
+		domain = strings.TrimSuffix(host, ">")
+	}
+	var msg bytes.Buffer
+	for _, h := range [][2]string{
+		{"From", from},
+		{"To", to},
+		{"Subject", mime.QEncoding.Encode("utf-8", d.Subject)},
+		{"Date", date.Format(time.RFC1123Z)},
+		{"Message-ID", fmt.Sprintf("<%s@%s>", uuid.New(), domain)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "multipart/alternative; boundary=" + mw.Boundary()},
+	} {
+		fmt.Fprintf(&msg, "%s: %s\r\n", h[0], h[1])
+	}
+	msg.WriteString("\r\n")
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+// mailer delivers digests.
+type mailer interface {
+	send(from, to string, msg []byte) error
+}
+
+// smtpMailer sends mail through the SMTP server in the config.
+type smtpMailer struct {
+	addr string
+	auth smtp.Auth
+}
+
+func (m smtpMailer) send(from, to string, msg []byte) error {
+	return smtp.SendMail(m.addr, m.auth, from, []string{to}, msg)
+}
+
+// emlMailer writes each message to a .eml file in dir instead of sending it,
+// for testing templates without an SMTP server.
+type emlMailer struct {
+	dir string
+}
+
+func (m emlMailer) send(from, to string, msg []byte) error {
+	name := fmt.Sprintf("digest-%s-%s.eml", to, time.Now().Format("20060102-150405"))
+	return os.WriteFile(filepath.Join(m.dir, name), msg, 0o644)
+}
+
+// newMailer returns the mailer and sender address for digests: .eml files
+// in emlDir if it is set, otherwise the SMTP server from the config.
+func newMailer(cfg *config.Config, emlDir string) (mailer, string, error) {
+	if emlDir != "" {
+		if err := os.MkdirAll(emlDir, 0o755); err != nil {
+			return nil, "", err
+		}
+		from := cfg.SMTPFrom
+		if from == "" {
+			from = digestFrom
+		}
+		return emlMailer{dir: emlDir}, from, nil
+	}
+
+	addr := cfg.SMTPAddr()
+	if addr == "" {
+		return nil, "", errors.New("no SMTP server configured; set smtp_host in the config or use --eml")
+	}
+	if cfg.SMTPFrom == "" {
+		return nil, "", errors.New("smtp_from must be set in the config to send digests")
+	}
+	m := smtpMailer{addr: addr}
+	if cfg.SMTPUsername != "" {
+		m.auth = smtp.PlainAuth("", cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPHost)
+	}
+	return m, cfg.SMTPFrom, nil
+}
+
+// sendDigest mails user the posts that arrived since the cutoff and weren't
+// in an earlier digest, and records them as sent. It returns how many posts
+// were sent; nothing is sent when there are none.
+func sendDigest(ctx context.Context, s *state, m mailer, from string, user database.User, since time.Time) (int, error) {
+	posts, err := s.db.GetDigestPosts(ctx, database.GetDigestPostsParams{
+		UserID: user.ID,
+		Since:  since,
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(posts) == 0 {
+		return 0, nil
+	}
+
+	now := time.Now()
+	msg, err := buildDigest(user, since, posts).message(from, user.Email.String, now)
+	if err != nil {
+		return 0, fmt.Errorf("render digest: %w", err)
+	}
+	if err := m.send(from, user.Email.String, msg); err != nil {
+		return 0, fmt.Errorf("send digest: %w", err)
+	}
+
+	ids := make([]uuid.UUID, len(posts))
+	for i, p := range posts {
+		ids[i] = p.ID
+	}
+	// if this fails the posts are sent again next time, which beats
+	// losing them
+	if _, err := s.db.RecordDigestPosts(ctx, database.RecordDigestPostsParams{
+		UserID:  user.ID,
+		SentAt:  now,
+		PostIds: ids,
+	}); err != nil {
+		return 0, fmt.Errorf("record digest: %w", err)
+	}
+	return len(posts), nil
+}
+
+func handlerDigest(s *state, cmd command, user database.User) error {
+	fs := newFlagSet("digest")
+	since := fs.Duration("since", 24*time.Hour, "include posts that arrived this long ago")
+	all := fs.Bool("all", false, "send a digest to every user with an email address")
+	emlDir := fs.String("eml", "", "write .eml files to this directory instead of sending mail")
+	args, err := parseArgs(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return errors.New("digest takes no arguments")
+	}
+	if *since <= 0 {
+		return errors.New("digest --since must be positive")
+	}
+
+	m, from, err := newMailer(s.cfg, *emlDir)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	users := []database.User{user}
+	if *all {
+		if users, err = s.db.GetUsers(ctx); err != nil {
+			return err
+		}
+	}
+
+	cutoff := time.Now().Add(-*since)
+	failed := 0
+	for _, u := range users {
+		if !u.Email.Valid {
+			if !*all {
+				return fmt.Errorf("%s has no email address; set one with setemail", u.Name)
+			}
+			continue
+		}
+
+		n, err := sendDigest(ctx, s, m, from, u, cutoff)
+		if err != nil {
+			if !*all {
+				return err
+			}
+			log.Printf("error sending digest (user=%s): %v", u.Name, err)
+			failed++
+			continue
+		}
+		if n == 0 {
+			fmt.Printf("no new posts for %s\n", u.Name)
+		} else {
+			fmt.Printf("sent %d posts to %s <%s>\n", n, u.Name, u.Email.String)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d digests failed", failed)
+	}
+	return nil
+}
+
+func handlerSetEmail(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 1 {
+		return errors.New("setemail requires an email address, or none to remove it")
+	}
+
+	var email sql.NullString
+	if cmd.args[0] != "none" {
+		addr, err := mail.ParseAddress(cmd.args[0])
+		if err != nil {
+			return fmt.Errorf("invalid email address: %s", cmd.args[0])
+		}
+		email = sql.NullString{String: addr.Address, Valid: true}
+	}
+
+	updated, err := s.db.SetUserEmail(context.Background(), database.SetUserEmailParams{
+		ID:        user.ID,
+		Email:     email,
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	if !updated.Email.Valid {
+		fmt.Printf("removed the email address of %s\n", updated.Name)
+		return nil
+	}
+	fmt.Printf("digests for %s go to %s\n", updated.Name, updated.Email.String)
+	return nil
+}
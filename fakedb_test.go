@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"gator/internal/database"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeDB is a database/sql connector for tests that don't have Postgres.
+// Each query is answered by the function registered under its sqlc name,
+// taken from the "-- name:" line sqlc starts every query with.
+type fakeDB struct {
+	mu      sync.Mutex
+	queries map[string]fakeQuery
+}
+
+// fakeQuery answers one query given its arguments, already converted to
+// driver values (uuids as strings, pq arrays as array literals). For exec
+// queries the number of rows returned is the number of rows affected.
+type fakeQuery func(args []driver.Value) ([][]driver.Value, error)
+
+func newFakeDB() *fakeDB {
+	return &fakeDB{queries: map[string]fakeQuery{}}
+}
+
+// on registers q as the answer to the query called name.
+func (db *fakeDB) on(name string, q fakeQuery) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.queries[name] = q
+}
+
+// state returns a state whose queries go to db.
+func (db *fakeDB) state(t *testing.T) *state {
+	t.Helper()
+	conn := sql.OpenDB(db)
+	t.Cleanup(func() { conn.Close() })
+	return &state{db: database.New(conn)}
+}
+
+func (db *fakeDB) run(query string, named []driver.NamedValue) ([][]driver.Value, error) {
+	first, _, _ := strings.Cut(query, "\n")
+	fields := strings.Fields(strings.TrimPrefix(first, "-- name:"))
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("fakedb: query without a name: %q", first)
+	}
+
+	db.mu.Lock()
+	q, ok := db.queries[fields[0]]
+	db.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("fakedb: unexpected query %s", fields[0])
+	}
+
+	args := make([]driver.Value, len(named))
+	for i, a := range named {
+		args[i] = a.Value
+	}
+	return q(args)
+}
+
+func (db *fakeDB) Connect(context.Context) (driver.Conn, error) {
+	return fakeConn{db}, nil
+}
+
+func (db *fakeDB) Driver() driver.Driver {
+	return fakeDriver{}
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("fakedb: use sql.OpenDB")
+}
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("fakedb: prepared statements are not supported")
+}
+
+func (c fakeConn) Close() error {
+	return nil
+}
+
+func (c fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("fakedb: transactions are not supported")
+}
+
+func (c fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	rows, err := c.db.run(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRows{rows: rows}, nil
+}
+
+func (c fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	rows, err := c.db.run(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(len(rows)), nil
+}
+
+type fakeRows struct {
+	rows [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	if len(r.rows) == 0 {
+		return nil
+	}
+	return make([]string, len(r.rows[0]))
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// fakeRow builds a result row from the values of a row struct's fields,
+// in the order the query selects them.
+func fakeRow(values ...any) []driver.Value {
+	row := make([]driver.Value, len(values))
+	for i, v := range values {
+		dv, err := driver.DefaultParameterConverter.ConvertValue(v)
+		if err != nil {
+			panic(fmt.Sprintf("fakedb: column %d: %v", i, err))
+		}
+		row[i] = dv
+	}
+	return row
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"gator/internal/database"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// recordFetch writes one scrape attempt to the fetch log. Failing to log is
+// not worth failing the scrape over, so errors are only reported.
+func recordFetch(s *state, feed database.Feed, startedAt time.Time, stats fetchStats, itemsSeen, inserted int, fetchErr error) {
+	params := database.CreateFeedFetchParams{
+		ID:            uuid.New(),
+		FeedID:        feed.ID,
+		StartedAt:     startedAt,
+		FinishedAt:    time.Now(),
+		ItemsSeen:     int32(itemsSeen),
+		PostsInserted: int32(inserted),
+	}
+	if stats.StatusCode != 0 {
+		params.HttpStatus = sql.NullInt32{Int32: int32(stats.StatusCode), Valid: true}
+		params.Bytes = sql.NullInt64{Int64: stats.Bytes, Valid: true}
+	}
+	if fetchErr != nil {
+		params.Error = sql.NullString{String: fetchErr.Error(), Valid: true}
+	}
+
+	if err := s.db.CreateFeedFetch(context.Background(), params); err != nil {
+		log.Printf("error recording fetch (url=%s): %v", feed.Url, err)
+	}
+}
+
+// fetchLogPruneInterval is how often agg drops old fetch log entries.
+const fetchLogPruneInterval = time.Hour
+
+// pruneFetchLog drops fetch log entries older than retention.
+func pruneFetchLog(s *state, retention time.Duration) {
+	n, err := s.db.DeleteFeedFetchesBefore(context.Background(), time.Now().Add(-retention))
+	if err != nil {
+		log.Printf("error pruning fetch log: %v", err)
+		return
+	}
+	if n > 0 {
+		fmt.Printf("pruned %d fetch log entries\n", n)
+	}
+}
+
+func handlerFetchLog(s *state, cmd command) error {
+	fs := newFlagSet("fetchlog")
+	limit := fs.Int("limit", 20, "number of attempts to show")
+	args, err := parseArgs(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	if len(args) > 1 {
+		return errors.New("fetchlog takes an optional feed url")
+	}
+	if *limit <= 0 {
+		return errors.New("fetchlog --limit must be a positive integer")
+	}
+
+	var feedURL sql.NullString
+	if len(args) == 1 {
+		feedURL = sql.NullString{String: args[0], Valid: true}
+		if _, err := s.db.GetFeedByURL(context.Background(), args[0]); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("feed not found: %s", args[0])
+			}
+			return err
+		}
+	}
+
+	fetches, err := s.db.GetFeedFetches(context.Background(), database.GetFeedFetchesParams{
+		FeedUrl: feedURL,
+		Limit:   int32(*limit),
+	})
+	if err != nil {
+		return err
+	}
+
+	l := newListing("started_at", "finished_at", "feed_name", "feed_url",
+		"http_status", "bytes", "items_seen", "posts_inserted", "error")
+	for _, f := range fetches {
+		l.add(f.StartedAt, f.FinishedAt, f.FeedName, f.FeedUrl,
+			f.HttpStatus, f.Bytes, f.ItemsSeen, f.PostsInserted, f.Error)
+	}
+
+	return render(s, l, func() {
+		for _, f := range fetches {
+			status := "---"
+			if f.HttpStatus.Valid {
+				status = fmt.Sprint(f.HttpStatus.Int32)
+			}
+			took := f.FinishedAt.Sub(f.StartedAt).Round(time.Millisecond)
+
+			fmt.Printf("* %s  %s  %s\n", f.StartedAt.Format(time.DateTime), status, f.FeedName)
+			fmt.Printf("  took %s, %d bytes, %d items, %d new posts\n", took, f.Bytes.Int64, f.ItemsSeen, f.PostsInserted)
+			if f.Error.Valid {
+				fmt.Printf("  error: %s\n", f.Error.String)
+			}
+		}
+	})
+}
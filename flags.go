@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// newFlagSet returns a flag set for a command's options. Errors are returned
+// to the caller rather than printed, so they surface like any other command
+// error.
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return fs
+}
+
+// parseArgs parses fs from args and returns the positional arguments.
+// Unlike fs.Parse, flags may appear after positional arguments too, so
+// both `agg 1m --workers 4` and `agg --workers 4 1m` work.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// stringList is a flag that can be repeated, e.g. `--feed a --feed b`.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// parseTimeFlag reads a point in time given as a date (2006-01-02), an
+// RFC 3339 timestamp, or a duration meaning that long ago (e.g. 48h). The
+// second result is true when only a date was given.
+func parseTimeFlag(name, value string) (time.Time, bool, error) {
+	if t, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		return t, true, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.Local(), false, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return time.Now().Add(-d), false, nil
+	}
+	return time.Time{}, false, fmt.Errorf("--%s must be a date (2006-01-02), a timestamp, or a duration ago (48h)", name)
+}
@@ -3,8 +3,11 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
+	"strconv"
+	"time"
 )
 
 const configFileName = ".gatorconfig.json"
@@ -13,8 +16,45 @@ const configFileName = ".gatorconfig.json"
 type Config struct {
 	DBURL           string `json:"db_url"`
 	CurrentUserName string `json:"current_user_name"`
+
+	// Per-host politeness limits used by agg. Zero values fall back to the
+	// defaults below.
+	HostMaxConcurrency int    `json:"host_max_concurrency,omitempty"`
+	HostMinInterval    string `json:"host_min_interval,omitempty"`
+
+	// Bounds for the adaptive per-feed polling interval.
+	MinPollInterval string `json:"min_poll_interval,omitempty"`
+	MaxPollInterval string `json:"max_poll_interval,omitempty"`
+
+	// How long agg keeps entries in the fetch log.
+	FetchLogRetention string `json:"fetch_log_retention,omitempty"`
+
+	// Post retention used by prune. Empty/zero disables that rule.
+	RetentionMaxAge          string `json:"retention_max_age,omitempty"`
+	RetentionMaxPostsPerFeed int    `json:"retention_max_posts_per_feed,omitempty"`
+
+	// SMTP server digest sends mail through. Username and password are
+	// optional; the server must offer STARTTLS for them to be sent.
+	SMTPHost     string `json:"smtp_host,omitempty"`
+	SMTPPort     int    `json:"smtp_port,omitempty"`
+	SMTPUsername string `json:"smtp_username,omitempty"`
+	SMTPPassword string `json:"smtp_password,omitempty"`
+	SMTPFrom     string `json:"smtp_from,omitempty"`
+
+	// Shell command agg runs for each match of an alert rule with notify
+	// set, e.g. to show a desktop notification.
+	AlertCommand string `json:"alert_command,omitempty"`
 }
 
+const (
+	defaultHostMaxConcurrency = 2
+	defaultHostMinInterval    = 2 * time.Second
+	defaultMinPollInterval    = 10 * time.Minute
+	defaultMaxPollInterval    = 24 * time.Hour
+	defaultFetchLogRetention  = 7 * 24 * time.Hour
+	defaultSMTPPort           = 587
+)
+
 // Read reads ~/.gatorconfig.json and returns a Config struct.
 func Read() (Config, error) {
 	path, err := getConfigFilePath()
@@ -41,6 +81,84 @@ func (c *Config) SetUser(username string) error {
 	return write(*c)
 }
 
+// MaxConcurrencyPerHost returns how many requests agg may have in flight
+// against a single host at once.
+func (c Config) MaxConcurrencyPerHost() int {
+	if c.HostMaxConcurrency <= 0 {
+		return defaultHostMaxConcurrency
+	}
+	return c.HostMaxConcurrency
+}
+
+// MinIntervalPerHost returns the minimum delay between the start of two
+// requests to the same host.
+func (c Config) MinIntervalPerHost() (time.Duration, error) {
+	return parseDuration("host_min_interval", c.HostMinInterval, defaultHostMinInterval)
+}
+
+// PollIntervalBounds returns the lower and upper bound for how often agg
+// polls a single feed.
+func (c Config) PollIntervalBounds() (time.Duration, time.Duration, error) {
+	minInterval, err := parseDuration("min_poll_interval", c.MinPollInterval, defaultMinPollInterval)
+	if err != nil {
+		return 0, 0, err
+	}
+	maxInterval, err := parseDuration("max_poll_interval", c.MaxPollInterval, defaultMaxPollInterval)
+	if err != nil {
+		return 0, 0, err
+	}
+	if minInterval > maxInterval {
+		return 0, 0, fmt.Errorf("min_poll_interval (%s) is greater than max_poll_interval (%s)", minInterval, maxInterval)
+	}
+	return minInterval, maxInterval, nil
+}
+
+// FetchLogRetentionPeriod returns how long fetch attempts are kept before
+// agg prunes them.
+func (c Config) FetchLogRetentionPeriod() (time.Duration, error) {
+	return parseDuration("fetch_log_retention", c.FetchLogRetention, defaultFetchLogRetention)
+}
+
+// RetentionPolicy returns the configured maximum post age and maximum number
+// of posts kept per feed. Zero means the rule is disabled.
+func (c Config) RetentionPolicy() (time.Duration, int, error) {
+	maxAge, err := parseDuration("retention_max_age", c.RetentionMaxAge, 0)
+	if err != nil {
+		return 0, 0, err
+	}
+	if c.RetentionMaxPostsPerFeed < 0 {
+		return 0, 0, fmt.Errorf("retention_max_posts_per_feed must not be negative")
+	}
+	return maxAge, c.RetentionMaxPostsPerFeed, nil
+}
+
+// SMTPAddr returns the host:port digest sends mail to, or "" if no SMTP
+// server is configured.
+func (c Config) SMTPAddr() string {
+	if c.SMTPHost == "" {
+		return ""
+	}
+	port := c.SMTPPort
+	if port <= 0 {
+		port = defaultSMTPPort
+	}
+	return net.JoinHostPort(c.SMTPHost, strconv.Itoa(port))
+}
+
+func parseDuration(field, value string, def time.Duration) (time.Duration, error) {
+	if value == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("parse %s: %w", field, err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("%s must not be negative", field)
+	}
+	return d, nil
+}
+
 func getConfigFilePath() (string, error) {
 	home, err := os.UserHomeDir()
 	if err != nil {
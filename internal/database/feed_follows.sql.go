@@ -10,13 +10,14 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createFeedFollow = `-- name: CreateFeedFollow :one
 WITH inserted AS (
   INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id)
   VALUES ($1, $2, $3, $4, $5)
-  RETURNING id, created_at, updated_at, user_id, feed_id
+  RETURNING id, created_at, updated_at, user_id, feed_id, tags
 )
 SELECT
   inserted.id,
@@ -92,8 +93,10 @@ SELECT
   ff.updated_at,
   ff.user_id,
   ff.feed_id,
+  ff.tags,
   users.name AS user_name,
-  feeds.name AS feed_name
+  feeds.name AS feed_name,
+  feeds.url AS feed_url
 FROM feed_follows ff
 JOIN users ON users.id = ff.user_id
 JOIN feeds ON feeds.id = ff.feed_id
@@ -107,8 +110,10 @@ type GetFeedFollowsForUserRow struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	Tags      []string
 	UserName  string
 	FeedName  string
+	FeedUrl   string
 }
 
 func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowsForUserRow, error) {
@@ -126,8 +131,10 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 			&i.UpdatedAt,
 			&i.UserID,
 			&i.FeedID,
+			pq.Array(&i.Tags),
 			&i.UserName,
 			&i.FeedName,
+			&i.FeedUrl,
 		); err != nil {
 			return nil, err
 		}
@@ -141,3 +148,61 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 	}
 	return items, nil
 }
+
+const tagFeedFollow = `-- name: TagFeedFollow :execrows
+UPDATE feed_follows
+SET tags = ARRAY(
+    SELECT DISTINCT t FROM unnest(array_append(tags, $1::text)) AS t ORDER BY t
+  ),
+  updated_at = $2
+WHERE user_id = $3 AND feed_id = $4
+`
+
+type TagFeedFollowParams struct {
+	Tag       string
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	FeedID    uuid.UUID
+}
+
+func (q *Queries) TagFeedFollow(ctx context.Context, arg TagFeedFollowParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, tagFeedFollow,
+		arg.Tag,
+		arg.UpdatedAt,
+		arg.UserID,
+		arg.FeedID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const untagFeedFollow = `-- name: UntagFeedFollow :execrows
+UPDATE feed_follows
+SET tags = array_remove(tags, $1::text),
+  updated_at = $2
+WHERE user_id = $3
+  AND feed_id = $4
+  AND $1::text = ANY(tags)
+`
+
+type UntagFeedFollowParams struct {
+	Tag       string
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	FeedID    uuid.UUID
+}
+
+func (q *Queries) UntagFeedFollow(ctx context.Context, arg UntagFeedFollowParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, untagFeedFollow,
+		arg.Tag,
+		arg.UpdatedAt,
+		arg.UserID,
+		arg.FeedID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
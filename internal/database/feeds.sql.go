@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,7 +16,7 @@ import (
 const createFeed = `-- name: CreateFeed :one
 INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
 VALUES ($1, $2, $3, $4, $5, $6)
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, next_fetch_at, poll_interval, refresh_interval
 `
 
 type CreateFeedParams struct {
@@ -45,12 +46,65 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.NextFetchAt,
+		&i.PollInterval,
+		&i.RefreshInterval,
+	)
+	return i, err
+}
+
+const deleteFeed = `-- name: DeleteFeed :execrows
+DELETE FROM feeds
+WHERE id = $1
+`
+
+func (q *Queries) DeleteFeed(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFeed, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getEarliestNextFetch = `-- name: GetEarliestNextFetch :one
+SELECT next_fetch_at
+FROM feeds
+ORDER BY next_fetch_at NULLS FIRST
+LIMIT 1
+`
+
+func (q *Queries) GetEarliestNextFetch(ctx context.Context) (sql.NullTime, error) {
+	row := q.db.QueryRowContext(ctx, getEarliestNextFetch)
+	var next_fetch_at sql.NullTime
+	err := row.Scan(&next_fetch_at)
+	return next_fetch_at, err
+}
+
+const getFeedByID = `-- name: GetFeedByID :one
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, next_fetch_at, poll_interval, refresh_interval FROM feeds
+WHERE id = $1
+`
+
+func (q *Queries) GetFeedByID(ctx context.Context, id uuid.UUID) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeedByID, id)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.NextFetchAt,
+		&i.PollInterval,
+		&i.RefreshInterval,
 	)
 	return i, err
 }
 
 const getFeedByURL = `-- name: GetFeedByURL :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, next_fetch_at, poll_interval, refresh_interval FROM feeds
 WHERE url = $1
 `
 
@@ -65,10 +119,73 @@ func (q *Queries) GetFeedByURL(ctx context.Context, url string) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.NextFetchAt,
+		&i.PollInterval,
+		&i.RefreshInterval,
 	)
 	return i, err
 }
 
+const getFeedStats = `-- name: GetFeedStats :many
+SELECT
+  feeds.id,
+  feeds.name,
+  feeds.url,
+  feeds.last_fetched_at,
+  feeds.next_fetch_at,
+  feeds.poll_interval,
+  feeds.refresh_interval,
+  COUNT(posts.id) AS post_count
+FROM feeds
+LEFT JOIN posts ON posts.feed_id = feeds.id
+WHERE $1::text IS NULL OR feeds.url = $1::text
+GROUP BY feeds.id
+ORDER BY feeds.name ASC
+`
+
+type GetFeedStatsRow struct {
+	ID              uuid.UUID
+	Name            string
+	Url             string
+	LastFetchedAt   sql.NullTime
+	NextFetchAt     sql.NullTime
+	PollInterval    sql.NullInt32
+	RefreshInterval sql.NullInt32
+	PostCount       int64
+}
+
+func (q *Queries) GetFeedStats(ctx context.Context, url sql.NullString) ([]GetFeedStatsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedStats, url)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFeedStatsRow
+	for rows.Next() {
+		var i GetFeedStatsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Url,
+			&i.LastFetchedAt,
+			&i.NextFetchAt,
+			&i.PollInterval,
+			&i.RefreshInterval,
+			&i.PostCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getFeeds = `-- name: GetFeeds :many
 SELECT
   feeds.id,
@@ -124,36 +241,122 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
 	return items, nil
 }
 
-const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at
+const getNextFeedsToFetch = `-- name: GetNextFeedsToFetch :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, next_fetch_at, poll_interval, refresh_interval
 FROM feeds
-ORDER BY last_fetched_at NULLS FIRST, created_at ASC
-LIMIT 1
+WHERE next_fetch_at IS NULL OR next_fetch_at <= $1::timestamp
+ORDER BY next_fetch_at NULLS FIRST, created_at ASC
+LIMIT $2
 `
 
-func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
-	row := q.db.QueryRowContext(ctx, getNextFeedToFetch)
-	var i Feed
-	err := row.Scan(
-		&i.ID,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-		&i.Name,
-		&i.Url,
-		&i.UserID,
-		&i.LastFetchedAt,
-	)
-	return i, err
+type GetNextFeedsToFetchParams struct {
+	Now   time.Time
+	Limit int32
+}
+
+func (q *Queries) GetNextFeedsToFetch(ctx context.Context, arg GetNextFeedsToFetchParams) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getNextFeedsToFetch, arg.Now, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.NextFetchAt,
+			&i.PollInterval,
+			&i.RefreshInterval,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
 const markFeedFetched = `-- name: MarkFeedFetched :exec
 UPDATE feeds
 SET last_fetched_at = NOW(),
+    next_fetch_at = $2,
     updated_at = NOW()
 WHERE id = $1
 `
 
-func (q *Queries) MarkFeedFetched(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, markFeedFetched, id)
+type MarkFeedFetchedParams struct {
+	ID          uuid.UUID
+	NextFetchAt sql.NullTime
+}
+
+func (q *Queries) MarkFeedFetched(ctx context.Context, arg MarkFeedFetchedParams) error {
+	_, err := q.db.ExecContext(ctx, markFeedFetched, arg.ID, arg.NextFetchAt)
+	return err
+}
+
+const setFeedNextFetch = `-- name: SetFeedNextFetch :exec
+UPDATE feeds
+SET next_fetch_at = $2,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+type SetFeedNextFetchParams struct {
+	ID          uuid.UUID
+	NextFetchAt sql.NullTime
+}
+
+func (q *Queries) SetFeedNextFetch(ctx context.Context, arg SetFeedNextFetchParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedNextFetch, arg.ID, arg.NextFetchAt)
+	return err
+}
+
+const setFeedRefreshInterval = `-- name: SetFeedRefreshInterval :exec
+UPDATE feeds
+SET refresh_interval = $2,
+    next_fetch_at = $3,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+type SetFeedRefreshIntervalParams struct {
+	ID              uuid.UUID
+	RefreshInterval sql.NullInt32
+	NextFetchAt     sql.NullTime
+}
+
+func (q *Queries) SetFeedRefreshInterval(ctx context.Context, arg SetFeedRefreshIntervalParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedRefreshInterval, arg.ID, arg.RefreshInterval, arg.NextFetchAt)
+	return err
+}
+
+const setFeedSchedule = `-- name: SetFeedSchedule :exec
+UPDATE feeds
+SET poll_interval = $2,
+    next_fetch_at = $3,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+type SetFeedScheduleParams struct {
+	ID           uuid.UUID
+	PollInterval sql.NullInt32
+	NextFetchAt  sql.NullTime
+}
+
+func (q *Queries) SetFeedSchedule(ctx context.Context, arg SetFeedScheduleParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedSchedule, arg.ID, arg.PollInterval, arg.NextFetchAt)
 	return err
 }
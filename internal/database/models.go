@@ -11,14 +11,62 @@ import (
 	"github.com/google/uuid"
 )
 
+type Alert struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	RuleID    uuid.UUID
+	PostID    uuid.UUID
+}
+
+type AlertRule struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	FeedID    uuid.NullUUID
+	Kind      string
+	Pattern   string
+	Notify    bool
+}
+
+type ApiToken struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UserID     uuid.UUID
+	Name       string
+	TokenHash  string
+	Prefix     string
+	LastUsedAt sql.NullTime
+}
+
+type DigestPost struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+	SentAt time.Time
+}
+
 type Feed struct {
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Name            string
+	Url             string
+	UserID          uuid.UUID
+	LastFetchedAt   sql.NullTime
+	NextFetchAt     sql.NullTime
+	PollInterval    sql.NullInt32
+	RefreshInterval sql.NullInt32
+}
+
+type FeedFetch struct {
 	ID            uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	Name          string
-	Url           string
-	UserID        uuid.UUID
-	LastFetchedAt sql.NullTime
+	FeedID        uuid.UUID
+	StartedAt     time.Time
+	FinishedAt    time.Time
+	HttpStatus    sql.NullInt32
+	Bytes         sql.NullInt64
+	ItemsSeen     int32
+	PostsInserted int32
+	Error         sql.NullString
 }
 
 type FeedFollow struct {
@@ -27,17 +75,64 @@ type FeedFollow struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	Tags      []string
+}
+
+type MuteRule struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	FeedID    uuid.NullUUID
+	Kind      string
+	Pattern   string
 }
 
 type Post struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	Title          string
+	Url            string
+	Description    sql.NullString
+	PublishedAt    sql.NullTime
+	FeedID         uuid.UUID
+	Content        sql.NullString
+	Search         interface{}
+	RawDescription sql.NullString
+	RawContent     sql.NullString
+	Author         sql.NullString
+	Categories     []string
+	Sanitized      bool
+}
+
+type PostRead struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+	ReadAt time.Time
+}
+
+type PrunedPost struct {
+	Url      string
+	FeedID   uuid.UUID
+	PrunedAt time.Time
+}
+
+type PublishedFeed struct {
+	UserID    uuid.UUID
+	CreatedAt time.Time
+	Secret    string
+}
+
+type SavedPost struct {
 	ID          uuid.UUID
 	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	UserID      uuid.UUID
+	PostID      uuid.NullUUID
+	FeedName    string
 	Title       string
 	Url         string
 	Description sql.NullString
 	PublishedAt sql.NullTime
-	FeedID      uuid.UUID
 }
 
 type User struct {
@@ -45,4 +140,39 @@ type User struct {
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	Name      string
+	Email     sql.NullString
+}
+
+type Webhook struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	FeedID    uuid.NullUUID
+	Url       string
+	Secret    string
+}
+
+type WebhookDelivery struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	WebhookID     uuid.UUID
+	PostID        uuid.UUID
+	Status        string
+	Attempts      int32
+	NextAttemptAt time.Time
+	LastAttemptAt sql.NullTime
+	LastStatus    sql.NullInt32
+	LastError     sql.NullString
+}
+
+type WebsubSubscription struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	FeedID         uuid.UUID
+	HubUrl         string
+	TopicUrl       string
+	Secret         string
+	State          string
+	LeaseExpiresAt sql.NullTime
 }
@@ -11,30 +11,99 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const countPrunablePosts = `-- name: CountPrunablePosts :many
+WITH ranked AS (
+  SELECT
+    posts.id,
+    posts.feed_id,
+    COALESCE(posts.published_at, posts.created_at) AS posted_at,
+    ROW_NUMBER() OVER (
+      PARTITION BY posts.feed_id
+      ORDER BY posts.published_at DESC NULLS LAST, posts.created_at DESC
+    ) AS position
+  FROM posts
+)
+SELECT feeds.name, feeds.url, COUNT(*) AS prunable
+FROM ranked
+JOIN feeds ON feeds.id = ranked.feed_id
+WHERE (
+    ($1::bool AND ranked.posted_at < $2::timestamp)
+    OR ($3::int > 0 AND ranked.position > $3::int)
+  )
+  AND NOT EXISTS (SELECT 1 FROM saved_posts WHERE saved_posts.post_id = ranked.id)
+GROUP BY feeds.id, feeds.name, feeds.url
+ORDER BY feeds.name ASC
+`
+
+type CountPrunablePostsParams struct {
+	PruneByAge bool
+	Cutoff     time.Time
+	MaxPosts   int32
+}
+
+type CountPrunablePostsRow struct {
+	Name     string
+	Url      string
+	Prunable int64
+}
+
+// Posts older than the cutoff, or beyond the newest max_posts of their feed.
+// Saved posts are never pruned.
+func (q *Queries) CountPrunablePosts(ctx context.Context, arg CountPrunablePostsParams) ([]CountPrunablePostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, countPrunablePosts, arg.PruneByAge, arg.Cutoff, arg.MaxPosts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CountPrunablePostsRow
+	for rows.Next() {
+		var i CountPrunablePostsRow
+		if err := rows.Scan(&i.Name, &i.Url, &i.Prunable); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const createPost = `-- name: CreatePost :one
 INSERT INTO posts (
   id, created_at, updated_at,
   title, url, description, published_at,
-  feed_id
+  feed_id, content, raw_description, raw_content,
+  author, categories
 ) VALUES (
   $1, $2, $3,
   $4, $5, $6, $7,
-  $8
+  $8, $9, $10, $11,
+  $12, $13
 )
-RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, content, search, raw_description, raw_content, author, categories, sanitized
 `
 
 type CreatePostParams struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description sql.NullString
-	PublishedAt sql.NullTime
-	FeedID      uuid.UUID
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	Title          string
+	Url            string
+	Description    sql.NullString
+	PublishedAt    sql.NullTime
+	FeedID         uuid.UUID
+	Content        sql.NullString
+	RawDescription sql.NullString
+	RawContent     sql.NullString
+	Author         sql.NullString
+	Categories     []string
 }
 
 func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
@@ -47,6 +116,11 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		arg.Description,
 		arg.PublishedAt,
 		arg.FeedID,
+		arg.Content,
+		arg.RawDescription,
+		arg.RawContent,
+		arg.Author,
+		pq.Array(arg.Categories),
 	)
 	var i Post
 	err := row.Scan(
@@ -58,34 +132,496 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		&i.Description,
 		&i.PublishedAt,
 		&i.FeedID,
+		&i.Content,
+		&i.Search,
+		&i.RawDescription,
+		&i.RawContent,
+		&i.Author,
+		pq.Array(&i.Categories),
+		&i.Sanitized,
 	)
 	return i, err
 }
 
-const getPostsForUser = `-- name: GetPostsForUser :many
-SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id
+const deletePost = `-- name: DeletePost :execrows
+DELETE FROM posts
+WHERE id = $1
+`
+
+func (q *Queries) DeletePost(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deletePost, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deletePrunablePosts = `-- name: DeletePrunablePosts :execrows
+WITH ranked AS (
+  SELECT
+    posts.id,
+    posts.feed_id,
+    COALESCE(posts.published_at, posts.created_at) AS posted_at,
+    ROW_NUMBER() OVER (
+      PARTITION BY posts.feed_id
+      ORDER BY posts.published_at DESC NULLS LAST, posts.created_at DESC
+    ) AS position
+  FROM posts
+),
+deleted AS (
+  DELETE FROM posts
+  WHERE posts.id IN (
+    SELECT ranked.id
+    FROM ranked
+    WHERE (
+        ($1::bool AND ranked.posted_at < $2::timestamp)
+        OR ($3::int > 0 AND ranked.position > $3::int)
+      )
+      AND NOT EXISTS (SELECT 1 FROM saved_posts WHERE saved_posts.post_id = ranked.id)
+  )
+  RETURNING posts.url, posts.feed_id
+)
+INSERT INTO pruned_posts (url, feed_id, pruned_at)
+SELECT url, feed_id, $4::timestamp FROM deleted
+ON CONFLICT (url) DO UPDATE SET feed_id = EXCLUDED.feed_id, pruned_at = EXCLUDED.pruned_at
+`
+
+type DeletePrunablePostsParams struct {
+	PruneByAge bool
+	Cutoff     time.Time
+	MaxPosts   int32
+	PrunedAt   time.Time
+}
+
+// Deletes what CountPrunablePosts counts, remembering the URLs in
+// pruned_posts.
+func (q *Queries) DeletePrunablePosts(ctx context.Context, arg DeletePrunablePostsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deletePrunablePosts,
+		arg.PruneByAge,
+		arg.Cutoff,
+		arg.MaxPosts,
+		arg.PrunedAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deletePrunedPostsBefore = `-- name: DeletePrunedPostsBefore :execrows
+DELETE FROM pruned_posts
+WHERE pruned_at < $1
+`
+
+func (q *Queries) DeletePrunedPostsBefore(ctx context.Context, prunedAt time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deletePrunedPostsBefore, prunedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getFeedPublishTimes = `-- name: GetFeedPublishTimes :many
+SELECT published_at
+FROM posts
+WHERE feed_id = $1 AND published_at IS NOT NULL
+ORDER BY published_at DESC
+LIMIT $2
+`
+
+type GetFeedPublishTimesParams struct {
+	FeedID uuid.UUID
+	Limit  int32
+}
+
+func (q *Queries) GetFeedPublishTimes(ctx context.Context, arg GetFeedPublishTimesParams) ([]sql.NullTime, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedPublishTimes, arg.FeedID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []sql.NullTime
+	for rows.Next() {
+		var published_at sql.NullTime
+		if err := rows.Scan(&published_at); err != nil {
+			return nil, err
+		}
+		items = append(items, published_at)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFilteredPostsForUser = `-- name: GetFilteredPostsForUser :many
+SELECT
+  posts.id, posts.created_at, posts.updated_at,
+  posts.title, posts.url, posts.description, posts.published_at,
+  posts.feed_id, posts.content,
+  feeds.name AS feed_name
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+JOIN feed_follows ON feed_follows.feed_id = feeds.id
+WHERE feed_follows.user_id = $1
+  AND (
+    NOT $2::bool
+    OR NOT EXISTS (
+      SELECT 1 FROM post_reads
+      WHERE post_reads.post_id = posts.id
+        AND post_reads.user_id = $1
+    )
+  )
+  AND (
+    COALESCE(cardinality($3::text[]), 0) = 0
+    OR feeds.url = ANY($3::text[])
+    OR feeds.name = ANY($3::text[])
+  )
+  AND (
+    COALESCE(cardinality($4::text[]), 0) = 0
+    OR feed_follows.tags && $4::text[]
+  )
+  AND COALESCE(posts.published_at, posts.created_at) >= COALESCE($5::timestamp, '-infinity'::timestamp)
+  AND COALESCE(posts.published_at, posts.created_at) < COALESCE($6::timestamp, 'infinity'::timestamp)
+  AND (
+    posts.title ILIKE '%' || $7::text || '%'
+    OR posts.description ILIKE '%' || $7::text || '%'
+  )
+  AND (
+    $8::text = ''
+    OR (
+      $8::text = 'before'
+      AND (COALESCE(posts.published_at, '0001-01-01'::timestamp), posts.created_at, posts.id)
+        < ($9::timestamp, $10::timestamp, $11::uuid)
+    )
+    OR (
+      $8::text = 'after'
+      AND (COALESCE(posts.published_at, '0001-01-01'::timestamp), posts.created_at, posts.id)
+        > ($9::timestamp, $10::timestamp, $11::uuid)
+    )
+  )
+  AND NOT EXISTS (
+    SELECT 1 FROM mute_rules
+    WHERE mute_rules.user_id = $1
+      AND (mute_rules.feed_id IS NULL OR mute_rules.feed_id = posts.feed_id)
+      AND (
+        (mute_rules.kind = 'title' AND posts.title ~* mute_rules.pattern)
+        OR (mute_rules.kind = 'category' AND lower(mute_rules.pattern) IN (SELECT lower(c) FROM unnest(posts.categories) AS c))
+        OR (mute_rules.kind = 'author' AND strpos(lower(posts.author), lower(mute_rules.pattern)) > 0)
+      )
+  )
+ORDER BY
+  CASE WHEN $8::text = 'after' THEN COALESCE(posts.published_at, '0001-01-01'::timestamp) END ASC,
+  CASE WHEN $8::text = 'after' THEN posts.created_at END ASC,
+  CASE WHEN $8::text = 'after' THEN posts.id END ASC,
+  COALESCE(posts.published_at, '0001-01-01'::timestamp) DESC,
+  posts.created_at DESC,
+  posts.id DESC
+LIMIT $12
+OFFSET $13
+`
+
+type GetFilteredPostsForUserParams struct {
+	UserID            uuid.UUID
+	UnreadOnly        bool
+	Feeds             []string
+	Tags              []string
+	Since             sql.NullTime
+	Until             sql.NullTime
+	Keyword           string
+	CursorDirection   string
+	CursorPublishedAt time.Time
+	CursorCreatedAt   time.Time
+	CursorID          uuid.UUID
+	Limit             int32
+	Offset            int32
+}
+
+type GetFilteredPostsForUserRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedID      uuid.UUID
+	Content     sql.NullString
+	FeedName    string
+}
+
+// Like GetPostsForUser, but only returns posts that pass every filter.
+// Empty/NULL filters match everything.
+func (q *Queries) GetFilteredPostsForUser(ctx context.Context, arg GetFilteredPostsForUserParams) ([]GetFilteredPostsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFilteredPostsForUser,
+		arg.UserID,
+		arg.UnreadOnly,
+		pq.Array(arg.Feeds),
+		pq.Array(arg.Tags),
+		arg.Since,
+		arg.Until,
+		arg.Keyword,
+		arg.CursorDirection,
+		arg.CursorPublishedAt,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.Limit,
+		arg.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFilteredPostsForUserRow
+	for rows.Next() {
+		var i GetFilteredPostsForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Content,
+			&i.FeedName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFilteredPostsForUserWithMuted = `-- name: GetFilteredPostsForUserWithMuted :many
+SELECT
+  posts.id, posts.created_at, posts.updated_at,
+  posts.title, posts.url, posts.description, posts.published_at,
+  posts.feed_id, posts.content,
+  feeds.name AS feed_name
 FROM posts
 JOIN feeds ON posts.feed_id = feeds.id
 JOIN feed_follows ON feed_follows.feed_id = feeds.id
 WHERE feed_follows.user_id = $1
-ORDER BY posts.published_at DESC NULLS LAST, posts.created_at DESC
+  AND (
+    NOT $2::bool
+    OR NOT EXISTS (
+      SELECT 1 FROM post_reads
+      WHERE post_reads.post_id = posts.id
+        AND post_reads.user_id = $1
+    )
+  )
+  AND (
+    COALESCE(cardinality($3::text[]), 0) = 0
+    OR feeds.url = ANY($3::text[])
+    OR feeds.name = ANY($3::text[])
+  )
+  AND (
+    COALESCE(cardinality($4::text[]), 0) = 0
+    OR feed_follows.tags && $4::text[]
+  )
+  AND COALESCE(posts.published_at, posts.created_at) >= COALESCE($5::timestamp, '-infinity'::timestamp)
+  AND COALESCE(posts.published_at, posts.created_at) < COALESCE($6::timestamp, 'infinity'::timestamp)
+  AND (
+    posts.title ILIKE '%' || $7::text || '%'
+    OR posts.description ILIKE '%' || $7::text || '%'
+  )
+  AND (
+    $8::text = ''
+    OR (
+      $8::text = 'before'
+      AND (COALESCE(posts.published_at, '0001-01-01'::timestamp), posts.created_at, posts.id)
+        < ($9::timestamp, $10::timestamp, $11::uuid)
+    )
+    OR (
+      $8::text = 'after'
+      AND (COALESCE(posts.published_at, '0001-01-01'::timestamp), posts.created_at, posts.id)
+        > ($9::timestamp, $10::timestamp, $11::uuid)
+    )
+  )
+ORDER BY
+  CASE WHEN $8::text = 'after' THEN COALESCE(posts.published_at, '0001-01-01'::timestamp) END ASC,
+  CASE WHEN $8::text = 'after' THEN posts.created_at END ASC,
+  CASE WHEN $8::text = 'after' THEN posts.id END ASC,
+  COALESCE(posts.published_at, '0001-01-01'::timestamp) DESC,
+  posts.created_at DESC,
+  posts.id DESC
+LIMIT $12
+OFFSET $13
+`
+
+type GetFilteredPostsForUserWithMutedParams struct {
+	UserID            uuid.UUID
+	UnreadOnly        bool
+	Feeds             []string
+	Tags              []string
+	Since             sql.NullTime
+	Until             sql.NullTime
+	Keyword           string
+	CursorDirection   string
+	CursorPublishedAt time.Time
+	CursorCreatedAt   time.Time
+	CursorID          uuid.UUID
+	Limit             int32
+	Offset            int32
+}
+
+type GetFilteredPostsForUserWithMutedRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedID      uuid.UUID
+	Content     sql.NullString
+	FeedName    string
+}
+
+// Like GetFilteredPostsForUser, but keeps the posts the user muted.
+func (q *Queries) GetFilteredPostsForUserWithMuted(ctx context.Context, arg GetFilteredPostsForUserWithMutedParams) ([]GetFilteredPostsForUserWithMutedRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFilteredPostsForUserWithMuted,
+		arg.UserID,
+		arg.UnreadOnly,
+		pq.Array(arg.Feeds),
+		pq.Array(arg.Tags),
+		arg.Since,
+		arg.Until,
+		arg.Keyword,
+		arg.CursorDirection,
+		arg.CursorPublishedAt,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.Limit,
+		arg.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFilteredPostsForUserWithMutedRow
+	for rows.Next() {
+		var i GetFilteredPostsForUserWithMutedRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Content,
+			&i.FeedName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostByID = `-- name: GetPostByID :one
+SELECT
+  posts.id, posts.created_at, posts.updated_at,
+  posts.title, posts.url, posts.description, posts.published_at,
+  posts.feed_id, posts.content,
+  feeds.name AS feed_name
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+WHERE posts.id = $1
+`
+
+type GetPostByIDRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedID      uuid.UUID
+	Content     sql.NullString
+	FeedName    string
+}
+
+func (q *Queries) GetPostByID(ctx context.Context, id uuid.UUID) (GetPostByIDRow, error) {
+	row := q.db.QueryRowContext(ctx, getPostByID, id)
+	var i GetPostByIDRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Content,
+		&i.FeedName,
+	)
+	return i, err
+}
+
+const getPostsForFeed = `-- name: GetPostsForFeed :many
+SELECT
+  posts.id, posts.created_at, posts.updated_at,
+  posts.title, posts.url, posts.description, posts.published_at,
+  posts.feed_id, posts.content,
+  feeds.name AS feed_name
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+WHERE posts.feed_id = $1
+ORDER BY posts.published_at DESC NULLS LAST, posts.created_at DESC, posts.id DESC
 LIMIT $2
+OFFSET $3
 `
 
-type GetPostsForUserParams struct {
-	UserID uuid.UUID
+type GetPostsForFeedParams struct {
+	FeedID uuid.UUID
 	Limit  int32
+	Offset int32
 }
 
-func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]Post, error) {
-	rows, err := q.db.QueryContext(ctx, getPostsForUser, arg.UserID, arg.Limit)
+type GetPostsForFeedRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedID      uuid.UUID
+	Content     sql.NullString
+	FeedName    string
+}
+
+func (q *Queries) GetPostsForFeed(ctx context.Context, arg GetPostsForFeedParams) ([]GetPostsForFeedRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsForFeed, arg.FeedID, arg.Limit, arg.Offset)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Post
+	var items []GetPostsForFeedRow
 	for rows.Next() {
-		var i Post
+		var i GetPostsForFeedRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.CreatedAt,
@@ -95,6 +631,8 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 			&i.Description,
 			&i.PublishedAt,
 			&i.FeedID,
+			&i.Content,
+			&i.FeedName,
 		); err != nil {
 			return nil, err
 		}
@@ -108,3 +646,406 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 	}
 	return items, nil
 }
+
+const getPostsForUser = `-- name: GetPostsForUser :many
+SELECT
+  posts.id, posts.created_at, posts.updated_at,
+  posts.title, posts.url, posts.description, posts.published_at,
+  posts.feed_id, posts.content,
+  feeds.name AS feed_name
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+JOIN feed_follows ON feed_follows.feed_id = feeds.id
+WHERE feed_follows.user_id = $1
+  AND (
+    $2::text = ''
+    OR (
+      $2::text = 'before'
+      AND (COALESCE(posts.published_at, '0001-01-01'::timestamp), posts.created_at, posts.id)
+        < ($3::timestamp, $4::timestamp, $5::uuid)
+    )
+    OR (
+      $2::text = 'after'
+      AND (COALESCE(posts.published_at, '0001-01-01'::timestamp), posts.created_at, posts.id)
+        > ($3::timestamp, $4::timestamp, $5::uuid)
+    )
+  )
+  AND NOT EXISTS (
+    SELECT 1 FROM mute_rules
+    WHERE mute_rules.user_id = $1
+      AND (mute_rules.feed_id IS NULL OR mute_rules.feed_id = posts.feed_id)
+      AND (
+        (mute_rules.kind = 'title' AND posts.title ~* mute_rules.pattern)
+        OR (mute_rules.kind = 'category' AND lower(mute_rules.pattern) IN (SELECT lower(c) FROM unnest(posts.categories) AS c))
+        OR (mute_rules.kind = 'author' AND strpos(lower(posts.author), lower(mute_rules.pattern)) > 0)
+      )
+  )
+ORDER BY
+  CASE WHEN $2::text = 'after' THEN COALESCE(posts.published_at, '0001-01-01'::timestamp) END ASC,
+  CASE WHEN $2::text = 'after' THEN posts.created_at END ASC,
+  CASE WHEN $2::text = 'after' THEN posts.id END ASC,
+  COALESCE(posts.published_at, '0001-01-01'::timestamp) DESC,
+  posts.created_at DESC,
+  posts.id DESC
+LIMIT $6
+OFFSET $7
+`
+
+type GetPostsForUserParams struct {
+	UserID            uuid.UUID
+	CursorDirection   string
+	CursorPublishedAt time.Time
+	CursorCreatedAt   time.Time
+	CursorID          uuid.UUID
+	Limit             int32
+	Offset            int32
+}
+
+type GetPostsForUserRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedID      uuid.UUID
+	Content     sql.NullString
+	FeedName    string
+}
+
+// Keyset pagination on (published_at, created_at, id), newest first. Posts
+// without published_at sort as if published at 0001-01-01, i.e. last.
+// cursor_direction is empty, 'before' (older than the cursor) or 'after'
+// (newer than the cursor; returned oldest first, callers reverse them).
+// Posts the user muted are left out.
+func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]GetPostsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsForUser,
+		arg.UserID,
+		arg.CursorDirection,
+		arg.CursorPublishedAt,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.Limit,
+		arg.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPostsForUserRow
+	for rows.Next() {
+		var i GetPostsForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Content,
+			&i.FeedName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostsForUserWithMuted = `-- name: GetPostsForUserWithMuted :many
+SELECT
+  posts.id, posts.created_at, posts.updated_at,
+  posts.title, posts.url, posts.description, posts.published_at,
+  posts.feed_id, posts.content,
+  feeds.name AS feed_name
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+JOIN feed_follows ON feed_follows.feed_id = feeds.id
+WHERE feed_follows.user_id = $1
+  AND (
+    $2::text = ''
+    OR (
+      $2::text = 'before'
+      AND (COALESCE(posts.published_at, '0001-01-01'::timestamp), posts.created_at, posts.id)
+        < ($3::timestamp, $4::timestamp, $5::uuid)
+    )
+    OR (
+      $2::text = 'after'
+      AND (COALESCE(posts.published_at, '0001-01-01'::timestamp), posts.created_at, posts.id)
+        > ($3::timestamp, $4::timestamp, $5::uuid)
+    )
+  )
+ORDER BY
+  CASE WHEN $2::text = 'after' THEN COALESCE(posts.published_at, '0001-01-01'::timestamp) END ASC,
+  CASE WHEN $2::text = 'after' THEN posts.created_at END ASC,
+  CASE WHEN $2::text = 'after' THEN posts.id END ASC,
+  COALESCE(posts.published_at, '0001-01-01'::timestamp) DESC,
+  posts.created_at DESC,
+  posts.id DESC
+LIMIT $6
+OFFSET $7
+`
+
+type GetPostsForUserWithMutedParams struct {
+	UserID            uuid.UUID
+	CursorDirection   string
+	CursorPublishedAt time.Time
+	CursorCreatedAt   time.Time
+	CursorID          uuid.UUID
+	Limit             int32
+	Offset            int32
+}
+
+type GetPostsForUserWithMutedRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedID      uuid.UUID
+	Content     sql.NullString
+	FeedName    string
+}
+
+// Like GetPostsForUser, but keeps the posts the user muted.
+func (q *Queries) GetPostsForUserWithMuted(ctx context.Context, arg GetPostsForUserWithMutedParams) ([]GetPostsForUserWithMutedRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsForUserWithMuted,
+		arg.UserID,
+		arg.CursorDirection,
+		arg.CursorPublishedAt,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.Limit,
+		arg.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPostsForUserWithMutedRow
+	for rows.Next() {
+		var i GetPostsForUserWithMutedRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Content,
+			&i.FeedName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostsToSanitize = `-- name: GetPostsToSanitize :many
+SELECT id, url, raw_description, raw_content
+FROM posts
+WHERE id > $1
+  AND (NOT $2::bool OR NOT sanitized)
+ORDER BY id
+LIMIT $3
+`
+
+type GetPostsToSanitizeParams struct {
+	ID              uuid.UUID
+	UnsanitizedOnly bool
+	Limit           int32
+}
+
+type GetPostsToSanitizeRow struct {
+	ID             uuid.UUID
+	Url            string
+	RawDescription sql.NullString
+	RawContent     sql.NullString
+}
+
+// Pages through posts by id, for re-running the sanitizer over the original
+// content: all of them, or only those never sanitized.
+func (q *Queries) GetPostsToSanitize(ctx context.Context, arg GetPostsToSanitizeParams) ([]GetPostsToSanitizeRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsToSanitize, arg.ID, arg.UnsanitizedOnly, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPostsToSanitizeRow
+	for rows.Next() {
+		var i GetPostsToSanitizeRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Url,
+			&i.RawDescription,
+			&i.RawContent,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPrunedURLs = `-- name: GetPrunedURLs :many
+SELECT url FROM pruned_posts
+WHERE url = ANY($1::text[])
+`
+
+// Which of urls belong to pruned posts.
+func (q *Queries) GetPrunedURLs(ctx context.Context, urls []string) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getPrunedURLs, pq.Array(urls))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var url string
+		if err := rows.Scan(&url); err != nil {
+			return nil, err
+		}
+		items = append(items, url)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchPosts = `-- name: SearchPosts :many
+SELECT
+  posts.id,
+  posts.title,
+  posts.url,
+  posts.published_at,
+  feeds.name AS feed_name,
+  ts_rank(posts.search, query)::real AS rank,
+  ts_headline(
+    'english',
+    COALESCE(NULLIF(posts.description, ''), posts.content, posts.title),
+    query,
+    'StartSel=**, StopSel=**, MaxFragments=2, MinWords=8, MaxWords=24'
+  )::text AS snippet
+FROM posts
+JOIN feeds ON feeds.id = posts.feed_id
+CROSS JOIN websearch_to_tsquery('english', $1::text) AS query
+WHERE posts.search @@ query
+  AND (
+    $2::bool
+    OR EXISTS (
+      SELECT 1 FROM feed_follows
+      WHERE feed_follows.feed_id = posts.feed_id
+        AND feed_follows.user_id = $3
+    )
+  )
+ORDER BY rank DESC, posts.published_at DESC NULLS LAST
+LIMIT $4
+`
+
+type SearchPostsParams struct {
+	Query    string
+	AllFeeds bool
+	UserID   uuid.UUID
+	Limit    int32
+}
+
+type SearchPostsRow struct {
+	ID          uuid.UUID
+	Title       string
+	Url         string
+	PublishedAt sql.NullTime
+	FeedName    string
+	Rank        float32
+	Snippet     string
+}
+
+// Ranked full-text search, scoped to the user's followed feeds unless
+// all_feeds is set. Snippets mark matches with **.
+func (q *Queries) SearchPosts(ctx context.Context, arg SearchPostsParams) ([]SearchPostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchPosts,
+		arg.Query,
+		arg.AllFeeds,
+		arg.UserID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchPostsRow
+	for rows.Next() {
+		var i SearchPostsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Url,
+			&i.PublishedAt,
+			&i.FeedName,
+			&i.Rank,
+			&i.Snippet,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updatePostContent = `-- name: UpdatePostContent :exec
+UPDATE posts
+SET description = $2, content = $3, updated_at = $4, sanitized = TRUE
+WHERE id = $1
+`
+
+type UpdatePostContentParams struct {
+	ID          uuid.UUID
+	Description sql.NullString
+	Content     sql.NullString
+	UpdatedAt   time.Time
+}
+
+func (q *Queries) UpdatePostContent(ctx context.Context, arg UpdatePostContentParams) error {
+	_, err := q.db.ExecContext(ctx, updatePostContent,
+		arg.ID,
+		arg.Description,
+		arg.Content,
+		arg.UpdatedAt,
+	)
+	return err
+}
@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -20,7 +21,7 @@ VALUES (
   $3,
   $4
 )
-RETURNING id, created_at, updated_at, name
+RETURNING id, created_at, updated_at, name, email
 `
 
 type CreateUserParams struct {
@@ -43,12 +44,26 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Name,
+		&i.Email,
 	)
 	return i, err
 }
 
+const deleteUser = `-- name: DeleteUser :execrows
+DELETE FROM users
+WHERE name = $1
+`
+
+func (q *Queries) DeleteUser(ctx context.Context, name string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteUser, name)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const getUser = `-- name: GetUser :one
-SELECT id, created_at, updated_at, name
+SELECT id, created_at, updated_at, name, email
 FROM users
 WHERE name = $1
 `
@@ -61,12 +76,13 @@ func (q *Queries) GetUser(ctx context.Context, name string) (User, error) {
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Name,
+		&i.Email,
 	)
 	return i, err
 }
 
 const getUsers = `-- name: GetUsers :many
-SELECT id, created_at, updated_at, name FROM users
+SELECT id, created_at, updated_at, name, email FROM users
 ORDER BY created_at
 `
 
@@ -84,6 +100,7 @@ func (q *Queries) GetUsers(ctx context.Context) ([]User, error) {
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.Name,
+			&i.Email,
 		); err != nil {
 			return nil, err
 		}
@@ -106,3 +123,29 @@ func (q *Queries) ResetUsers(ctx context.Context) error {
 	_, err := q.db.ExecContext(ctx, resetUsers)
 	return err
 }
+
+const setUserEmail = `-- name: SetUserEmail :one
+UPDATE users
+SET email = $2, updated_at = $3
+WHERE id = $1
+RETURNING id, created_at, updated_at, name, email
+`
+
+type SetUserEmailParams struct {
+	ID        uuid.UUID
+	Email     sql.NullString
+	UpdatedAt time.Time
+}
+
+func (q *Queries) SetUserEmail(ctx context.Context, arg SetUserEmailParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserEmail, arg.ID, arg.Email, arg.UpdatedAt)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Email,
+	)
+	return i, err
+}
@@ -0,0 +1,205 @@
+package markup
+
+import (
+	"html"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// allowedTags are the elements Sanitize keeps, with the attributes each
+// may carry besides title.
+var allowedTags = map[string][]string{
+	"a": {"href"}, "abbr": nil, "b": nil, "blockquote": {"cite"}, "br": nil,
+	"caption": nil, "cite": nil, "code": nil, "dd": nil, "del": nil,
+	"details": nil, "dfn": nil, "div": nil, "dl": nil, "dt": nil, "em": nil,
+	"figcaption": nil, "figure": nil, "h1": nil, "h2": nil, "h3": nil,
+	"h4": nil, "h5": nil, "h6": nil, "hr": nil, "i": nil,
+	"img": {"src", "alt", "width", "height"}, "ins": nil, "kbd": nil,
+	"li": nil, "mark": nil, "ol": {"start"}, "p": nil, "pre": nil,
+	"q": {"cite"}, "s": nil, "samp": nil, "small": nil, "span": nil,
+	"strike": nil, "strong": nil, "sub": nil, "summary": nil, "sup": nil,
+	"table": nil, "tbody": nil, "td": {"colspan", "rowspan"}, "tfoot": nil,
+	"th": {"colspan", "rowspan"}, "thead": nil, "time": {"datetime"},
+	"tr": nil, "u": nil, "ul": nil, "var": nil,
+}
+
+// droppedTags are removed together with their content. Other tags that
+// aren't allowed are removed but their text is kept.
+var droppedTags = map[string]bool{
+	"script": true, "style": true, "iframe": true, "object": true,
+	"embed": true, "noscript": true, "template": true, "svg": true,
+	"math": true, "form": true, "textarea": true, "select": true,
+	"head": true, "title": true, "frameset": true, "applet": true,
+}
+
+var voidTags = map[string]bool{"br": true, "hr": true, "img": true}
+
+// urlAttrs hold URLs, which must be http(s) (or mailto for links) once
+// resolved.
+var urlAttrs = map[string]bool{"href": true, "src": true, "cite": true}
+
+// Sanitize returns src with everything not on the allowlist removed:
+// scripts, styles, event handler and style attributes, javascript: and
+// data: URLs, tracking pixels and unknown elements. Relative URLs are
+// resolved against base, the post's own link; when base is empty they are
+// dropped. The result is well-formed: every kept element is closed.
+func Sanitize(src, base string) string {
+	baseURL, err := url.Parse(base)
+	if err != nil || !baseURL.IsAbs() {
+		baseURL = nil
+	}
+
+	var b strings.Builder
+	var open []string
+	dropped := 0
+	for _, t := range tokenize(src) {
+		if dropped > 0 {
+			if droppedTags[t.data] {
+				switch t.kind {
+				case startTagToken:
+					dropped++
+				case endTagToken:
+					dropped--
+				}
+			}
+			continue
+		}
+
+		switch t.kind {
+		case textToken:
+			b.WriteString(html.EscapeString(t.data))
+		case startTagToken, selfClosingTagToken:
+			if droppedTags[t.data] {
+				if t.kind == startTagToken {
+					dropped++
+				}
+				continue
+			}
+			attrs, ok := allowedAttrs(t, baseURL)
+			if !ok {
+				continue
+			}
+			b.WriteString("<" + t.data)
+			for _, a := range attrs {
+				b.WriteString(" " + a.key + `="` + html.EscapeString(a.val) + `"`)
+			}
+			b.WriteString(">")
+			if !voidTags[t.data] {
+				if t.kind == selfClosingTagToken {
+					b.WriteString("</" + t.data + ">")
+				} else {
+					open = append(open, t.data)
+				}
+			}
+		case endTagToken:
+			// close up to the matching element; stray end tags are dropped
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] != t.data {
+					continue
+				}
+				for j := len(open) - 1; j >= i; j-- {
+					b.WriteString("</" + open[j] + ">")
+				}
+				open = open[:i]
+				break
+			}
+		}
+	}
+	for i := len(open) - 1; i >= 0; i-- {
+		b.WriteString("</" + open[i] + ">")
+	}
+	return b.String()
+}
+
+// allowedAttrs returns the attributes of t that Sanitize keeps, or false if
+// the element itself should go.
+func allowedAttrs(t token, base *url.URL) ([]attr, bool) {
+	allowed, ok := allowedTags[t.data]
+	if !ok {
+		return nil, false
+	}
+
+	var attrs []attr
+	for _, a := range t.attrs {
+		if a.key != "title" && !slices.Contains(allowed, a.key) {
+			continue
+		}
+		if urlAttrs[a.key] {
+			u, ok := safeURL(a.val, base, t.data == "a")
+			if !ok {
+				continue
+			}
+			a.val = u
+		}
+		if (a.key == "width" || a.key == "height" || a.key == "start" ||
+			a.key == "colspan" || a.key == "rowspan") && !isNumber(a.val) {
+			continue
+		}
+		attrs = append(attrs, a)
+	}
+
+	switch t.data {
+	case "a":
+		if hasAttr(attrs, "href") {
+			attrs = append(attrs, attr{key: "rel", val: "nofollow noopener noreferrer"})
+		}
+	case "img":
+		// images we can't load, and the 1x1 pixels used for tracking
+		if !hasAttr(attrs, "src") || isPixel(attrs, "width") || isPixel(attrs, "height") {
+			return nil, false
+		}
+	}
+	return attrs, true
+}
+
+// safeURL resolves raw against base and reports whether it is a URL we are
+// willing to link to.
+func safeURL(raw string, base *url.URL, mailto bool) (string, bool) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" || strings.ContainsFunc(raw, func(r rune) bool { return r < ' ' || r == 0x7f }) {
+		return "", false
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", false
+	}
+	if !u.IsAbs() {
+		if base == nil {
+			return "", false
+		}
+		u = base.ResolveReference(u)
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		return u.String(), true
+	case "mailto":
+		return u.String(), mailto
+	}
+	return "", false
+}
+
+func hasAttr(attrs []attr, key string) bool {
+	for _, a := range attrs {
+		if a.key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func isPixel(attrs []attr, key string) bool {
+	for _, a := range attrs {
+		if a.key == key {
+			n, err := strconv.Atoi(a.val)
+			return err == nil && n <= 1
+		}
+	}
+	return false
+}
+
+func isNumber(s string) bool {
+	n, err := strconv.Atoi(s)
+	return err == nil && n >= 0
+}
@@ -0,0 +1,106 @@
+package markup
+
+import "testing"
+
+func TestSanitize(t *testing.T) {
+	const base = "https://example.com/posts/1"
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"plain text", "Fish & chips <3", "Fish &amp; chips &lt;3"},
+		{"allowed markup", "<p>Hi <b>there</b></p>", "<p>Hi <b>there</b></p>"},
+		{"link", `<a href="https://example.org/">x</a>`, `<a href="https://example.org/" rel="nofollow noopener noreferrer">x</a>`},
+		{"mailto link", `<a href="mailto:me@example.org">x</a>`, `<a href="mailto:me@example.org" rel="nofollow noopener noreferrer">x</a>`},
+		{"mailto image", `<img src="mailto:me@example.org" alt="x">`, ""},
+
+		// URL schemes
+		{"javascript href", `<a href="javascript:alert(1)">x</a>`, "<a>x</a>"},
+		{"mixed case javascript", `<a href="JaVaScRiPt:alert(1)">x</a>`, "<a>x</a>"},
+		{"encoded tab in scheme", `<a href="JaVa&#x09;script:alert(1)">x</a>`, "<a>x</a>"},
+		{"encoded newline in scheme", `<a href="java&#10;script:alert(1)">x</a>`, "<a>x</a>"},
+		{"encoded colon", `<a href="javascript&colon;alert(1)">x</a>`, "<a>x</a>"},
+		{"entity encoded scheme", `<a href="&#106;avascript:alert(1)">x</a>`, "<a>x</a>"},
+		{"leading space", `<a href="  javascript:alert(1)">x</a>`, "<a>x</a>"},
+		{"raw control in scheme", "<a href=\"java\x00script:alert(1)\">x</a>", "<a>x</a>"},
+		{"vbscript href", `<a href="vbscript:msgbox(1)">x</a>`, "<a>x</a>"},
+		{"data href", `<a href="data:text/html;base64,PHNjcmlwdD4=">x</a>`, "<a>x</a>"},
+		{"data image", `<img src="data:image/svg+xml,<svg onload=alert(1)>">`, ""},
+		{"javascript cite", `<blockquote cite="javascript:alert(1)">q</blockquote>`, "<blockquote>q</blockquote>"},
+
+		// event handlers and styles
+		{"onerror", `<img src="https://example.org/a.png" onerror="alert(1)">`, `<img src="https://example.org/a.png">`},
+		{"onclick", `<p onclick="alert(1)">x</p>`, "<p>x</p>"},
+		{"onmouseover unquoted", `<b onmouseover=alert(1)>x</b>`, "<b>x</b>"},
+		{"style attribute", `<p style="background:url(javascript:alert(1))">x</p>`, "<p>x</p>"},
+		{"attribute breakout", `<p title="a&quot; onclick=&quot;alert(1)">x</p>`, `<p title="a&#34; onclick=&#34;alert(1)">x</p>`},
+		{"unknown attribute", `<a href="https://example.org/" target="_blank" id="x">x</a>`, `<a href="https://example.org/" rel="nofollow noopener noreferrer">x</a>`},
+
+		// dropped elements
+		{"script", "a<script>alert(1)</script>b", "ab"},
+		{"script upper case", "a<SCRIPT>alert(1)</SCRIPT >b", "ab"},
+		{"script holding a tag", `a<script>document.write("</p>")</script>b`, "ab"},
+		{"self-closed script", "a<script/>b", "ab"},
+		{"style", "a<style>body{display:none}</style>b", "ab"},
+		{"iframe", `a<iframe src="https://evil.example/"></iframe>b`, "ab"},
+		{"svg", `a<svg onload="alert(1)"><script>alert(1)</script></svg>b`, "ab"},
+		{"svg foreignObject", `<svg><foreignObject><img src=x onerror=alert(1)></foreignObject></svg>ok`, "ok"},
+		{"math", `a<math><mtext><table><mglyph><style><img src=x onerror=alert(1)>`, "a"},
+		{"nested dropped", "a<object><embed src=x></embed><object></object></object>b", "ab"},
+		{"form", `<form action="https://evil.example/"><input name="p"></form>ok`, "ok"},
+		{"unknown element keeps text", "<custom-tag>text</custom-tag>", "text"},
+		{"tracking pixel", `<img src="https://t.example/p.gif" width="1" height="1">`, ""},
+		{"image without src", `<img alt="x">`, ""},
+
+		// malformed input
+		{"unclosed elements", "<p><b>bold", "<p><b>bold</b></p>"},
+		{"misnested", "<b><i>x</b>y</i>", "<b><i>x</i></b>y"},
+		{"stray end tag", "x</div></p>y", "xy"},
+		{"unterminated tag", `ok<img src="https://example.org/a.png" onerror="alert(1)`, "ok"},
+		{"unterminated attribute", `ok<a href="javascript:alert(1)>x</a>`, "ok"},
+		{"lone angle bracket", "a < b > c", "a &lt; b &gt; c"},
+		{"tag without name", "<>x</>", "&lt;&gt;x&lt;/&gt;"},
+		{"null in tag name", "<scr\x00ipt>alert(1)</scr\x00ipt>", "alert(1)"},
+		{"comment", "a<!-- <script>alert(1)</script> -->b", "ab"},
+		{"unterminated comment", "a<!-- <script>alert(1)</script>", "a"},
+		{"conditional comment", "a<!--[if IE]><script>alert(1)</script><![endif]-->b", "ab"},
+		{"cdata", "<![CDATA[<script>alert(1)</script>]]>", "&lt;script&gt;alert(1)&lt;/script&gt;"},
+		{"unterminated cdata", "<![CDATA[<b>x", "&lt;b&gt;x"},
+		{"doctype", "<!DOCTYPE html><p>x</p>", "<p>x</p>"},
+		{"processing instruction", `<?xml version="1.0"?><p>x</p>`, "<p>x</p>"},
+		{"double angle", `<<script>alert(1)</script>`, "&lt;"},
+		{"non-numeric width", `<img src="https://example.org/a.png" width="100%">`, `<img src="https://example.org/a.png">`},
+
+		// relative URLs
+		{"relative href", `<a href="/about">x</a>`, `<a href="https://example.com/about" rel="nofollow noopener noreferrer">x</a>`},
+		{"relative path", `<img src="../img/a.png">`, `<img src="https://example.com/img/a.png">`},
+		{"protocol relative", `<img src="//cdn.example.org/a.png">`, `<img src="https://cdn.example.org/a.png">`},
+		{"fragment", `<a href="#note">x</a>`, `<a href="https://example.com/posts/1#note" rel="nofollow noopener noreferrer">x</a>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sanitize(tt.src, base); got != tt.want {
+				t.Errorf("Sanitize(%q)\n got %q\nwant %q", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeWithoutBase(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`<a href="/about">x</a>`, "<a>x</a>"},
+		{`<img src="a.png">`, ""},
+		{`<a href="https://example.org/">x</a>`, `<a href="https://example.org/" rel="nofollow noopener noreferrer">x</a>`},
+	}
+	for _, base := range []string{"", "not a url", "/relative/base"} {
+		for _, tt := range tests {
+			if got := Sanitize(tt.src, base); got != tt.want {
+				t.Errorf("Sanitize(%q, %q) = %q, want %q", tt.src, base, got, tt.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,386 @@
+// Package markup turns the HTML found in feeds into something safe to
+// store and pleasant to read.
+package markup
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ToText renders an HTML fragment as plain text wrapped to width columns.
+// Paragraphs, headings, lists, block quotes and code blocks keep their
+// shape; links become footnote references listed at the end; scripts,
+// styles and images without alt text are dropped.
+func ToText(src string, width int) string {
+	r := &textRenderer{width: max(width, 20)}
+	for _, t := range tokenize(src) {
+		r.token(t)
+	}
+	r.flush()
+	return r.String()
+}
+
+// Excerpt returns about the first n characters of the text of an HTML
+// fragment on a single line, without link references, cut at a word
+// boundary.
+func Excerpt(src string, n int) string {
+	r := &textRenderer{width: math.MaxInt, excerpt: true}
+	for _, t := range tokenize(src) {
+		r.token(t)
+	}
+	r.flush()
+	text := strings.Join(strings.Fields(strings.Join(r.lines, " ")), " ")
+	if utf8.RuneCountInString(text) <= n {
+		return text
+	}
+	cut := string([]rune(text)[:n])
+	if i := strings.LastIndexByte(cut, ' '); i > len(cut)/2 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " ,.;:") + "…"
+}
+
+// blockTags separate their content from the surrounding text with a blank
+// line; lineTags only start a new line.
+var (
+	blockTags = map[string]bool{
+		"p": true, "blockquote": true, "pre": true, "table": true, "figure": true,
+		"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+		"dl": true, "details": true, "section": true, "article": true,
+	}
+	lineTags = map[string]bool{
+		"div": true, "li": true, "tr": true, "dt": true, "dd": true,
+		"header": true, "footer": true, "figcaption": true, "summary": true,
+	}
+	// hiddenTags hold content that is never rendered.
+	hiddenTags = map[string]bool{
+		"head": true, "title": true, "template": true, "svg": true,
+		"noscript": true, "iframe": true, "object": true, "select": true,
+	}
+)
+
+type list struct {
+	ordered bool
+	n       int
+	hang    string // indent for the current item's continuation lines
+}
+
+type link struct {
+	href  string
+	start int // offset of the link text in the inline buffer
+}
+
+type textRenderer struct {
+	width   int
+	excerpt bool // no footnotes
+	lines   []string
+	blank   bool // a blank line is due before the next line of output
+
+	inline strings.Builder // text of the current line or paragraph
+	quote  int
+	lists  []list
+	marker string // list marker for the next line written
+	pre    int
+	code   strings.Builder // text of the current <pre>
+	hidden int
+	cells  int // cells seen in the current table row
+
+	open  []link
+	notes []string
+}
+
+func (r *textRenderer) token(t token) {
+	if r.hidden > 0 {
+		if hiddenTags[t.data] {
+			switch t.kind {
+			case startTagToken:
+				r.hidden++
+			case endTagToken:
+				r.hidden--
+			}
+		}
+		return
+	}
+
+	switch t.kind {
+	case textToken:
+		r.text(t.data)
+	case startTagToken, selfClosingTagToken:
+		r.start(t)
+		if t.kind == selfClosingTagToken {
+			r.end(t.data)
+		}
+	case endTagToken:
+		r.end(t.data)
+	}
+}
+
+func (r *textRenderer) text(s string) {
+	if r.pre > 0 {
+		r.code.WriteString(printable(s))
+		return
+	}
+	for _, c := range s {
+		if isSpaceRune(c) {
+			if r.inline.Len() == 0 || strings.HasSuffix(r.inline.String(), " ") {
+				continue
+			}
+			c = ' '
+		} else if unicode.IsControl(c) {
+			continue
+		}
+		r.inline.WriteRune(c)
+	}
+}
+
+func (r *textRenderer) start(t token) {
+	name := t.data
+	switch {
+	case hiddenTags[name]:
+		r.hidden++
+		return
+	case blockTags[name]:
+		r.flush()
+		r.blank = true
+	case lineTags[name]:
+		r.flush()
+	}
+
+	switch name {
+	case "br":
+		if r.pre > 0 {
+			r.code.WriteByte('\n')
+		} else {
+			r.flush()
+		}
+	case "hr":
+		r.flush()
+		r.blank = true
+		r.emit([]string{strings.Repeat("-", min(r.width, 40))})
+		r.blank = true
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		r.inline.WriteString(strings.Repeat("#", int(name[1]-'0')) + " ")
+	case "blockquote":
+		r.quote++
+	case "pre":
+		r.pre++
+	case "code", "kbd", "samp", "tt":
+		if r.pre == 0 {
+			r.inline.WriteByte('`')
+		}
+	case "ul", "ol":
+		r.flush()
+		if len(r.lists) == 0 {
+			r.blank = true
+		}
+		r.lists = append(r.lists, list{ordered: name == "ol"})
+	case "li":
+		if len(r.lists) == 0 {
+			r.lists = append(r.lists, list{})
+		}
+		l := &r.lists[len(r.lists)-1]
+		l.n++
+		r.marker = "• "
+		if l.ordered {
+			r.marker = fmt.Sprintf("%d. ", l.n)
+		}
+		l.hang = strings.Repeat(" ", utf8.RuneCountInString(r.marker))
+	case "tr":
+		r.cells = 0
+	case "td", "th":
+		if r.cells > 0 {
+			r.inline.WriteString(" | ")
+		}
+		r.cells++
+	case "a":
+		href, _ := t.attr("href")
+		r.open = append(r.open, link{href: printable(strings.TrimSpace(href)), start: r.inline.Len()})
+	case "img":
+		if alt, _ := t.attr("alt"); strings.TrimSpace(alt) != "" {
+			r.text("[image: " + strings.TrimSpace(alt) + "]")
+		}
+	}
+}
+
+func (r *textRenderer) end(name string) {
+	switch name {
+	case "blockquote":
+		r.flush()
+		r.quote = max(r.quote-1, 0)
+	case "pre":
+		if r.pre > 0 {
+			r.pre--
+		}
+		if r.pre == 0 {
+			r.flushCode()
+		}
+	case "code", "kbd", "samp", "tt":
+		if r.pre == 0 {
+			r.inline.WriteByte('`')
+		}
+	case "ul", "ol":
+		r.flush()
+		if len(r.lists) > 0 {
+			r.lists = r.lists[:len(r.lists)-1]
+		}
+		if len(r.lists) == 0 {
+			r.blank = true
+		}
+	case "a":
+		if len(r.open) == 0 {
+			return
+		}
+		l := r.open[len(r.open)-1]
+		r.open = r.open[:len(r.open)-1]
+		r.footnote(l)
+	}
+
+	switch {
+	case blockTags[name]:
+		r.flush()
+		r.blank = true
+	case lineTags[name]:
+		r.flush()
+	}
+}
+
+// footnote adds a reference to l after its text, unless the text already
+// shows where it goes.
+func (r *textRenderer) footnote(l link) {
+	if r.excerpt || l.href == "" || strings.HasPrefix(l.href, "#") || strings.HasPrefix(strings.ToLower(l.href), "javascript:") {
+		return
+	}
+	inline := r.inline.String()
+	if l.start <= len(inline) {
+		text := strings.TrimSpace(inline[l.start:])
+		if text == l.href || "http://"+text == l.href || "https://"+text == l.href {
+			return
+		}
+	}
+
+	n := 0
+	for i, href := range r.notes {
+		if href == l.href {
+			n = i + 1
+		}
+	}
+	if n == 0 {
+		r.notes = append(r.notes, l.href)
+		n = len(r.notes)
+	}
+	r.inline.WriteString(fmt.Sprintf("[%d]", n))
+}
+
+// prefixes returns the prefix for the first and following lines written
+// at the current nesting.
+func (r *textRenderer) prefixes() (string, string) {
+	base := strings.Repeat("> ", r.quote)
+	for _, l := range r.lists {
+		base += l.hang
+	}
+	if r.marker == "" || len(r.lists) == 0 {
+		return base, base
+	}
+	first := base[:len(base)-len(r.lists[len(r.lists)-1].hang)] + r.marker
+	r.marker = ""
+	return first, base
+}
+
+// flush wraps and writes the pending inline text.
+func (r *textRenderer) flush() {
+	text := strings.TrimSpace(r.inline.String())
+	r.inline.Reset()
+	if text == "" {
+		return
+	}
+	first, rest := r.prefixes()
+	r.emit(wrap(text, r.width, first, rest))
+}
+
+// flushCode writes the pending <pre> text verbatim, indented.
+func (r *textRenderer) flushCode() {
+	r.flush()
+	code := strings.Trim(r.code.String(), "\n")
+	r.code.Reset()
+	if strings.TrimSpace(code) == "" {
+		return
+	}
+
+	_, prefix := r.prefixes()
+	prefix += "    "
+	var lines []string
+	for _, line := range strings.Split(code, "\n") {
+		line = strings.TrimRight(strings.ReplaceAll(line, "\t", "    "), " \r")
+		if line == "" {
+			lines = append(lines, strings.TrimRight(prefix, " "))
+		} else {
+			lines = append(lines, prefix+line)
+		}
+	}
+	r.blank = true
+	r.emit(lines)
+	r.blank = true
+}
+
+func (r *textRenderer) emit(lines []string) {
+	if r.blank && len(r.lines) > 0 && r.lines[len(r.lines)-1] != "" {
+		r.lines = append(r.lines, "")
+	}
+	r.blank = false
+	r.lines = append(r.lines, lines...)
+}
+
+func (r *textRenderer) String() string {
+	lines := r.lines
+	if len(r.notes) > 0 {
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		for i, href := range r.notes {
+			lines = append(lines, fmt.Sprintf("[%d] %s", i+1, href))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// wrap breaks text into lines of at most width columns, starting the first
+// with first and the rest with rest. Words longer than a line, like URLs,
+// get a line of their own.
+func wrap(text string, width int, first, rest string) []string {
+	var lines []string
+	line, empty := first, true
+	for _, word := range strings.Fields(text) {
+		if !empty && utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) > width {
+			lines = append(lines, line)
+			line, empty = rest, true
+		}
+		if !empty {
+			line += " "
+		}
+		line += word
+		empty = false
+	}
+	if !empty {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// printable drops the control characters in s other than newlines and
+// tabs. Feeds could otherwise use escape sequences to restyle or clear the
+// reader's terminal.
+func printable(s string) string {
+	return strings.Map(func(c rune) rune {
+		if unicode.IsControl(c) && c != '\n' && c != '\t' {
+			return -1
+		}
+		return c
+	}, s)
+}
+
+func isSpaceRune(c rune) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
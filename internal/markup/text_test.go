@@ -0,0 +1,53 @@
+package markup
+
+import "testing"
+
+func TestToText(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"plain", "Hello <b>world</b>", "Hello world"},
+		{"entities", "Fish &amp; chips &lt;3 &#8212; &eacute;", "Fish & chips <3 — é"},
+		{"paragraphs", "<p>One</p><p>Two</p>", "One\n\nTwo"},
+		{"list", "<ul><li>a</li><li>b</li></ul>", "• a\n• b"},
+		{"link", `See <a href="https://example.com/x">this</a>.`, "See this[1].\n\n[1] https://example.com/x"},
+		{"script", "a<script>alert(1)</script>b", "ab"},
+
+		{"encoded escape", "Hello &#27;[2J world", "Hello [2J world"},
+		{"hex encoded escape", "Hello &#x1b;[31mred", "Hello [31mred"},
+		{"raw escape", "Hello \x1b[31mred\x1b[0m", "Hello [31mred[0m"},
+		{"C1 control", "a\u009b31mb", "a31mb"},
+		{"bell and backspace", "a\x07b\x08c", "abc"},
+		{"carriage return", "safe\rrm -rf", "safe rm -rf"},
+		{"escape in pre", "<pre>x\x1b[2Jy\n\tz</pre>", "    x[2Jy\n        z"},
+		{"escape in link", `<a href="https://e.x/&#27;]8;;">t</a>`, "t[1]\n\n[1] https://e.x/]8;;"},
+		{"escape in alt", `<img alt="&#27;[2Jcat">`, "[image: [2Jcat]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ToText(tt.src, 80); got != tt.want {
+				t.Errorf("ToText(%q) = %q, want %q", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	tests := []struct {
+		src  string
+		n    int
+		want string
+	}{
+		{"<p>Short</p>", 20, "Short"},
+		{"<p>One</p><p>Two</p>", 20, "One Two"},
+		{"The quick brown fox jumps", 16, "The quick brown…"},
+		{"bad &#27;[2Jescape", 40, "bad [2Jescape"},
+	}
+	for _, tt := range tests {
+		if got := Excerpt(tt.src, tt.n); got != tt.want {
+			t.Errorf("Excerpt(%q, %d) = %q, want %q", tt.src, tt.n, got, tt.want)
+		}
+	}
+}
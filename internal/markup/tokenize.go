@@ -0,0 +1,197 @@
+package markup
+
+import (
+	"html"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	textToken tokenKind = iota
+	startTagToken
+	endTagToken
+	selfClosingTagToken
+)
+
+type attr struct {
+	key, val string
+}
+
+// token is one piece of an HTML fragment. For text, data is the decoded
+// text; for tags, it is the lower-cased tag name.
+type token struct {
+	kind  tokenKind
+	data  string
+	attrs []attr
+}
+
+func (t token) attr(key string) (string, bool) {
+	for _, a := range t.attrs {
+		if a.key == key {
+			return a.val, true
+		}
+	}
+	return "", false
+}
+
+// rawTextTags hold text that is never displayed. The tokenizer drops their
+// contents, leaving just the start and end tags.
+var rawTextTags = map[string]bool{"script": true, "style": true}
+
+// tokenize splits an HTML fragment into tokens. It is forgiving in the way
+// browsers are: a '<' that doesn't start a tag is text, comments, doctypes
+// and processing instructions are dropped, and an unterminated tag ends
+// the input.
+func tokenize(src string) []token {
+	var tokens []token
+	var text strings.Builder
+	flushText := func() {
+		if text.Len() > 0 {
+			tokens = append(tokens, token{kind: textToken, data: html.UnescapeString(text.String())})
+			text.Reset()
+		}
+	}
+
+	for i := 0; i < len(src); {
+		lt := strings.IndexByte(src[i:], '<')
+		if lt < 0 {
+			text.WriteString(src[i:])
+			break
+		}
+		text.WriteString(src[i : i+lt])
+		i += lt
+
+		rest := src[i:]
+		switch {
+		case strings.HasPrefix(rest, "<!--"):
+			flushText()
+			end := strings.Index(rest[4:], "-->")
+			if end < 0 {
+				return tokens
+			}
+			i += 4 + end + 3
+		case strings.HasPrefix(rest, "<![CDATA["):
+			end := strings.Index(rest, "]]>")
+			if end < 0 {
+				end = len(rest)
+			}
+			// CDATA is literal text, so escape it for the final unescape
+			text.WriteString(html.EscapeString(rest[len("<![CDATA["):end]))
+			i += min(end+3, len(rest))
+		case strings.HasPrefix(rest, "<!") || strings.HasPrefix(rest, "<?"):
+			flushText()
+			end := strings.IndexByte(rest, '>')
+			if end < 0 {
+				return tokens
+			}
+			i += end + 1
+		case len(rest) > 1 && isLetter(rest[1]), len(rest) > 2 && rest[1] == '/' && isLetter(rest[2]):
+			flushText()
+			t, n, ok := parseTag(rest)
+			if !ok {
+				return tokens
+			}
+			tokens = append(tokens, t)
+			i += n
+			if t.kind == startTagToken && rawTextTags[t.data] {
+				end := indexFold(src[i:], "</"+t.data)
+				if end < 0 {
+					return tokens
+				}
+				i += end
+			}
+		default:
+			text.WriteByte('<')
+			i++
+		}
+	}
+	flushText()
+	return tokens
+}
+
+// parseTag reads the tag at the start of src and returns it with its
+// length in bytes.
+func parseTag(src string) (token, int, bool) {
+	t := token{kind: startTagToken}
+	i := 1
+	if src[i] == '/' {
+		t.kind = endTagToken
+		i++
+	}
+
+	start := i
+	for i < len(src) && !isSpace(src[i]) && src[i] != '/' && src[i] != '>' {
+		i++
+	}
+	t.data = strings.ToLower(src[start:i])
+
+	for i < len(src) {
+		for i < len(src) && (isSpace(src[i]) || src[i] == '/') {
+			if src[i] == '/' && i+1 < len(src) && src[i+1] == '>' && t.kind == startTagToken {
+				t.kind = selfClosingTagToken
+			}
+			i++
+		}
+		if i >= len(src) {
+			break
+		}
+		if src[i] == '>' {
+			return t, i + 1, true
+		}
+
+		start := i
+		for i < len(src) && !isSpace(src[i]) && src[i] != '=' && src[i] != '>' && src[i] != '/' {
+			i++
+		}
+		a := attr{key: strings.ToLower(src[start:i])}
+		for i < len(src) && isSpace(src[i]) {
+			i++
+		}
+		if i < len(src) && src[i] == '=' {
+			i++
+			for i < len(src) && isSpace(src[i]) {
+				i++
+			}
+			if i < len(src) && (src[i] == '"' || src[i] == '\'') {
+				quote := src[i]
+				end := strings.IndexByte(src[i+1:], quote)
+				if end < 0 {
+					return token{}, 0, false
+				}
+				a.val = src[i+1 : i+1+end]
+				i += end + 2
+			} else {
+				start := i
+				for i < len(src) && !isSpace(src[i]) && src[i] != '>' {
+					i++
+				}
+				a.val = src[start:i]
+			}
+			a.val = html.UnescapeString(a.val)
+		}
+		// end tags can't carry attributes; browsers ignore them
+		if t.kind == startTagToken && a.key != "" {
+			t.attrs = append(t.attrs, a)
+		}
+	}
+	return token{}, 0, false
+}
+
+func isLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+// indexFold is strings.Index, ignoring ASCII case.
+func indexFold(s, substr string) int {
+	for i := 0; i+len(substr) <= len(s); i++ {
+		if strings.EqualFold(s[i:i+len(substr)], substr) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// hostLimiter keeps agg polite towards servers that host many feeds
+// (medium.com, substack.com, ...). Every host gets a fixed number of
+// concurrent request slots, a minimum gap between request starts, and can
+// be blocked outright after a server asks us to back off via Retry-After.
+type hostLimiter struct {
+	maxConcurrency int
+	minInterval    time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+type hostState struct {
+	slots        chan struct{}
+	nextStart    time.Time // earliest time the next request may start
+	blockedUntil time.Time // set from Retry-After on 429/503 responses
+}
+
+func newHostLimiter(maxConcurrency int, minInterval time.Duration) *hostLimiter {
+	if maxConcurrency < 1 {
+		maxConcurrency = 1
+	}
+	return &hostLimiter{
+		maxConcurrency: maxConcurrency,
+		minInterval:    minInterval,
+		hosts:          make(map[string]*hostState),
+	}
+}
+
+func (l *hostLimiter) host(name string) *hostState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	h, ok := l.hosts[name]
+	if !ok {
+		h = &hostState{slots: make(chan struct{}, l.maxConcurrency)}
+		l.hosts[name] = h
+	}
+	return h
+}
+
+// acquire blocks until a request to host may start. The returned function
+// must be called once the request has finished to free the slot.
+func (l *hostLimiter) acquire(ctx context.Context, host string) (func(), error) {
+	h := l.host(host)
+
+	select {
+	case h.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	release := func() { <-h.slots }
+
+	// reserve a start time so concurrent callers space themselves out
+	l.mu.Lock()
+	now := time.Now()
+	start := now
+	if h.nextStart.After(start) {
+		start = h.nextStart
+	}
+	if h.blockedUntil.After(start) {
+		start = h.blockedUntil
+	}
+	h.nextStart = start.Add(l.minInterval)
+	l.mu.Unlock()
+
+	if wait := start.Sub(now); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+
+	return release, nil
+}
+
+// blockUntil stops new requests to host from starting before t.
+func (l *hostLimiter) blockUntil(host string, t time.Time) {
+	h := l.host(host)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if t.After(h.blockedUntil) {
+		h.blockedUntil = t
+	}
+}
+
+// blocked reports whether host is still backing off, and until when.
+func (l *hostLimiter) blocked(host string) (time.Time, bool) {
+	h := l.host(host)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return h.blockedUntil, time.Now().Before(h.blockedUntil)
+}
+
+// hostOf returns the lower-cased host (without port) of a feed URL, which
+// is the key the limiter groups requests by.
+func hostOf(feedURL string) string {
+	u, err := url.Parse(feedURL)
+	if err != nil || u.Hostname() == "" {
+		return feedURL
+	}
+	return strings.ToLower(u.Hostname())
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"gator/internal/database"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Kinds of mute rule. The GetPostsForUser queries and GetMuteRulesForUser match them.
+const (
+	// a case-insensitive Postgres regex over the title
+	muteTitle = "title"
+	// one of the post's categories, ignoring case
+	muteCategory = "category"
+	// part of the author's name, ignoring case
+	muteAuthor = "author"
+)
+
+func handlerMute(s *state, cmd command, user database.User) error {
+	fs := newFlagSet("mute")
+	feedURL := fs.String("feed", "", "only mute posts from this feed")
+	args, err := parseArgs(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 2 {
+		return errors.New("mute requires a kind (title, category or author) and a pattern")
+	}
+	kind, pattern := args[0], args[1]
+	if strings.TrimSpace(pattern) == "" {
+		return errors.New("mute pattern must not be empty")
+	}
+
+	ctx := context.Background()
+	switch kind {
+	case muteTitle:
+		// checked by Postgres, whose regex syntax is the one that counts
+		if _, err := s.db.CheckRegex(ctx, pattern); err != nil {
+			return fmt.Errorf("invalid regex: %w", err)
+		}
+	case muteCategory, muteAuthor:
+	default:
+		return fmt.Errorf("mute kind must be title, category or author, not %q", kind)
+	}
+
+	var feedID uuid.NullUUID
+	if *feedURL != "" {
+		feed, err := s.db.GetFeedByURL(ctx, *feedURL)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("feed not found: %s", *feedURL)
+			}
+			return err
+		}
+		feedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+	}
+
+	rule, err := s.db.CreateMuteRule(ctx, database.CreateMuteRuleParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UserID:    user.ID,
+		FeedID:    feedID,
+		Kind:      kind,
+		Pattern:   pattern,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("created mute rule %s\n", rule.ID)
+	return nil
+}
+
+func handlerMutes(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 0 {
+		return errors.New("mutes takes no arguments")
+	}
+
+	rules, err := s.db.GetMuteRulesForUser(context.Background(), user.ID)
+	if err != nil {
+		return err
+	}
+
+	l := newListing("id", "kind", "pattern", "feed_id", "feed_name", "muted", "created_at")
+	for _, r := range rules {
+		l.add(r.ID, r.Kind, r.Pattern, r.FeedID, r.FeedName, r.Muted, r.CreatedAt)
+	}
+	return render(s, l, func() {
+		for _, r := range rules {
+			fmt.Printf("* %s %q\n", r.Kind, r.Pattern)
+			fmt.Printf("  id: %s\n", r.ID)
+			if r.FeedName.Valid {
+				fmt.Printf("  feed: %s\n", r.FeedName.String)
+			} else {
+				fmt.Println("  feed: every feed")
+			}
+			fmt.Printf("  muted posts: %d\n", r.Muted)
+		}
+	})
+}
+
+func handlerUnmute(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 1 {
+		return errors.New("unmute requires a mute rule id")
+	}
+	id, err := uuid.Parse(cmd.args[0])
+	if err != nil {
+		return fmt.Errorf("invalid mute rule id: %s", cmd.args[0])
+	}
+
+	n, err := s.db.DeleteMuteRule(context.Background(), database.DeleteMuteRuleParams{
+		ID:     id,
+		UserID: user.ID,
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("mute rule not found: %s", id)
+	}
+	fmt.Printf("removed mute rule %s\n", id)
+	return nil
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"gator/internal/database"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// postCursor marks a position in the (published_at, created_at, id)
+// ordering GetPostsForUser pages through.
+type postCursor struct {
+	PublishedAt time.Time // zero for posts without published_at
+	CreatedAt   time.Time
+	ID          uuid.UUID
+}
+
+func cursorForPost(p database.GetPostsForUserRow) postCursor {
+	c := postCursor{CreatedAt: p.CreatedAt, ID: p.ID}
+	if p.PublishedAt.Valid {
+		c.PublishedAt = p.PublishedAt.Time
+	}
+	return c
+}
+
+// String encodes the cursor as an opaque token for --before/--after.
+func (c postCursor) String() string {
+	published := ""
+	if !c.PublishedAt.IsZero() {
+		published = c.PublishedAt.UTC().Format(time.RFC3339Nano)
+	}
+	raw := strings.Join([]string{published, c.CreatedAt.UTC().Format(time.RFC3339Nano), c.ID.String()}, "|")
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func parsePostCursor(token string) (postCursor, error) {
+	invalid := fmt.Errorf("invalid cursor: %s", token)
+
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return postCursor{}, invalid
+	}
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 3 {
+		return postCursor{}, invalid
+	}
+
+	var c postCursor
+	if parts[0] != "" {
+		if c.PublishedAt, err = time.Parse(time.RFC3339Nano, parts[0]); err != nil {
+			return postCursor{}, invalid
+		}
+	}
+	if c.CreatedAt, err = time.Parse(time.RFC3339Nano, parts[1]); err != nil {
+		return postCursor{}, invalid
+	}
+	if c.ID, err = uuid.Parse(parts[2]); err != nil {
+		return postCursor{}, invalid
+	}
+	return c, nil
+}
+
+// postQuery selects a page of the posts a user follows. postFilters and
+// pageOptions fill in its filters and cursor.
+type postQuery struct {
+	database.GetFilteredPostsForUserParams
+	showMuted bool
+}
+
+func newPostQuery(userID uuid.UUID, limit int32) postQuery {
+	var q postQuery
+	q.UserID = userID
+	q.Limit = limit
+	return q
+}
+
+// filtered reports whether any filter is set.
+func (q postQuery) filtered() bool {
+	return q.UnreadOnly || len(q.Feeds) > 0 || len(q.Tags) > 0 ||
+		q.Since.Valid || q.Until.Valid || q.Keyword != ""
+}
+
+// fetch runs the narrowest of the GetPostsForUser queries: the filter and
+// mute predicates are only in the SQL when they can remove posts.
+func (q postQuery) fetch(ctx context.Context, db *database.Queries) ([]database.GetPostsForUserRow, error) {
+	if q.filtered() {
+		if q.showMuted {
+			return postRows(db.GetFilteredPostsForUserWithMuted(ctx,
+				database.GetFilteredPostsForUserWithMutedParams(q.GetFilteredPostsForUserParams)))
+		}
+		return postRows(db.GetFilteredPostsForUser(ctx, q.GetFilteredPostsForUserParams))
+	}
+
+	params := database.GetPostsForUserParams{
+		UserID:            q.UserID,
+		CursorDirection:   q.CursorDirection,
+		CursorPublishedAt: q.CursorPublishedAt,
+		CursorCreatedAt:   q.CursorCreatedAt,
+		CursorID:          q.CursorID,
+		Limit:             q.Limit,
+		Offset:            q.Offset,
+	}
+	if q.showMuted {
+		return postRows(db.GetPostsForUserWithMuted(ctx, database.GetPostsForUserWithMutedParams(params)))
+	}
+	return db.GetPostsForUser(ctx, params)
+}
+
+// postRows converts the rows of the other GetPostsForUser queries, which
+// select the same columns.
+func postRows[T database.GetPostsForUserWithMutedRow | database.GetFilteredPostsForUserRow | database.GetFilteredPostsForUserWithMutedRow](rows []T, err error) ([]database.GetPostsForUserRow, error) {
+	if err != nil {
+		return nil, err
+	}
+	posts := make([]database.GetPostsForUserRow, len(rows))
+	for i, r := range rows {
+		posts[i] = database.GetPostsForUserRow(r)
+	}
+	return posts, nil
+}
+
+// pageOptions are the paging flags shared by post listing commands.
+type pageOptions struct {
+	before string
+	after  string
+	page   int
+}
+
+// apply fills the paging fields of q.
+func (o pageOptions) apply(params *postQuery) error {
+	if o.before != "" && o.after != "" {
+		return errors.New("--before and --after can't be used together")
+	}
+	if o.page < 1 {
+		return errors.New("--page must be at least 1")
+	}
+	params.Offset = int32(o.page-1) * params.Limit
+
+	token, direction := o.before, "before"
+	if o.after != "" {
+		token, direction = o.after, "after"
+	}
+	if token == "" {
+		return nil
+	}
+
+	c, err := parsePostCursor(token)
+	if err != nil {
+		return err
+	}
+	params.CursorDirection = direction
+	params.CursorPublishedAt = c.PublishedAt
+	params.CursorCreatedAt = c.CreatedAt
+	params.CursorID = c.ID
+	return nil
+}
+
+// pageCursors returns the cursors for the pages around posts, or "" where
+// there is no such page.
+func pageCursors(posts []database.GetPostsForUserRow, params postQuery) (newer, older string) {
+	if len(posts) == 0 {
+		return "", ""
+	}
+	if params.CursorDirection != "" || params.Offset > 0 {
+		newer = cursorForPost(posts[0]).String()
+	}
+	// a full page means there may be more
+	if len(posts) == int(params.Limit) {
+		older = cursorForPost(posts[len(posts)-1]).String()
+	}
+	return newer, older
+}
+
+// printPageCursors tells the user how to reach the pages around posts.
+func printPageCursors(posts []database.GetPostsForUserRow, params postQuery) {
+	newer, older := pageCursors(posts, params)
+	if newer != "" {
+		fmt.Printf("newer posts: --after %s\n", newer)
+	}
+	if older != "" {
+		fmt.Printf("older posts: --before %s\n", older)
+	}
+}
+
+// postFilters are the filters shared by post listing commands. Empty fields
+// match everything.
+type postFilters struct {
+	unreadOnly bool
+	feeds      []string // urls or names
+	tags       []string // any of these follow tags
+	since      string   // see parseTimeFlag
+	until      string
+	match      string // substring of the title or description
+	showMuted  bool   // include posts hidden by mute rules
+}
+
+// apply fills the filter fields of q.
+func (f postFilters) apply(params *postQuery) error {
+	params.UnreadOnly = f.unreadOnly
+	params.showMuted = f.showMuted
+	params.Keyword = escapeLike(f.match)
+	// empty rather than nil, which pq would send as NULL
+	params.Feeds = append([]string{}, f.feeds...)
+	params.Tags = make([]string, len(f.tags))
+	for i, tag := range f.tags {
+		tag, err := normalizeTag(tag)
+		if err != nil {
+			return err
+		}
+		params.Tags[i] = tag
+	}
+
+	if f.since != "" {
+		t, _, err := parseTimeFlag("since", f.since)
+		if err != nil {
+			return err
+		}
+		params.Since = sql.NullTime{Time: t, Valid: true}
+	}
+	if f.until != "" {
+		t, dateOnly, err := parseTimeFlag("until", f.until)
+		if err != nil {
+			return err
+		}
+		// a plain date includes that whole day
+		if dateOnly {
+			t = t.AddDate(0, 0, 1)
+		}
+		params.Until = sql.NullTime{Time: t, Valid: true}
+	}
+	return nil
+}
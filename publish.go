@@ -0,0 +1,321 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"gator/internal/database"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// publishLimit is how many posts a published feed carries by default.
+const publishLimit = 50
+
+// publishFormats maps the file names of a published feed to their format.
+var publishFormats = map[string]string{"rss.xml": "rss", "atom.xml": "atom"}
+
+// publishedFeedURL is where the user's feed is served in format, under the
+// base URL of `serve`.
+func publishedFeedURL(baseURL, secret, format string) string {
+	return strings.TrimRight(baseURL, "/") + "/feeds/" + secret + "/" + format + ".xml"
+}
+
+// publishedFeedSecret returns the secret in the URLs of the user's
+// published feed, creating one the first time or when rotate is set.
+func publishedFeedSecret(ctx context.Context, s *state, user database.User, rotate bool) (string, error) {
+	if !rotate {
+		feed, err := s.db.GetPublishedFeed(ctx, user.ID)
+		if err == nil {
+			return feed.Secret, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return "", err
+		}
+	}
+
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate secret: %w", err)
+	}
+	feed, err := s.db.SetPublishedFeedSecret(ctx, database.SetPublishedFeedSecretParams{
+		UserID:    user.ID,
+		CreatedAt: time.Now(),
+		Secret:    base64.RawURLEncoding.EncodeToString(b),
+	})
+	if err != nil {
+		return "", err
+	}
+	return feed.Secret, nil
+}
+
+// publishedFeed renders the newest posts from the feeds user follows as an
+// RSS or Atom document. The document only depends on the posts, so it can
+// be cached by its hash.
+func publishedFeed(ctx context.Context, s *state, user database.User, format, selfURL string, limit int32) ([]byte, error) {
+	params := newPostQuery(user.ID, limit)
+	if err := (postFilters{}).apply(&params); err != nil {
+		return nil, err
+	}
+	posts, err := params.fetch(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+
+	modified := fromTimestamp(user.CreatedAt)
+	for _, p := range posts {
+		if t := fromTimestamp(p.UpdatedAt); t.After(modified) {
+			modified = t
+		}
+	}
+
+	var doc any
+	if format == "atom" {
+		doc = atomDocument(user, posts, selfURL, modified)
+	} else {
+		doc = rssDocument(user, posts, selfURL, modified)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// postGUID identifies a post in published feeds. Post IDs never change, so
+// readers won't show a post twice.
+func postGUID(p database.GetPostsForUserRow) string {
+	return "urn:uuid:" + p.ID.String()
+}
+
+// postBody is the HTML published for a post.
+func postBody(p database.GetPostsForUserRow) string {
+	if p.Description.Valid {
+		return p.Description.String
+	}
+	return p.Content.String
+}
+
+type publishedRSS struct {
+	XMLName xml.Name            `xml:"rss"`
+	Version string              `xml:"version,attr"`
+	AtomNS  string              `xml:"xmlns:atom,attr"`
+	Channel publishedRSSChannel `xml:"channel"`
+}
+
+type publishedRSSChannel struct {
+	SelfLink      publishedAtomLink  `xml:"atom:link"`
+	Title         string             `xml:"title"`
+	Link          string             `xml:"link"`
+	Description   string             `xml:"description"`
+	LastBuildDate string             `xml:"lastBuildDate"`
+	Generator     string             `xml:"generator"`
+	Items         []publishedRSSItem `xml:"item"`
+}
+
+type publishedRSSItem struct {
+	Title       string           `xml:"title"`
+	Link        string           `xml:"link"`
+	GUID        publishedRSSGUID `xml:"guid"`
+	PubDate     string           `xml:"pubDate,omitempty"`
+	Category    string           `xml:"category,omitempty"`
+	Description string           `xml:"description,omitempty"`
+}
+
+type publishedRSSGUID struct {
+	IsPermaLink string `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+func rssDocument(user database.User, posts []database.GetPostsForUserRow, selfURL string, modified time.Time) publishedRSS {
+	doc := publishedRSS{
+		Version: "2.0",
+		AtomNS:  "http://www.w3.org/2005/Atom",
+		Channel: publishedRSSChannel{
+			SelfLink:      publishedAtomLink{Href: selfURL, Rel: "self", Type: "application/rss+xml"},
+			Title:         "gator: " + user.Name,
+			Link:          selfURL,
+			Description:   "Posts from the feeds " + user.Name + " follows in gator",
+			LastBuildDate: modified.Format(time.RFC1123Z),
+			Generator:     "gator",
+		},
+	}
+	for _, p := range posts {
+		item := publishedRSSItem{
+			Title:       p.Title,
+			Link:        p.Url,
+			GUID:        publishedRSSGUID{IsPermaLink: "false", Value: postGUID(p)},
+			Category:    p.FeedName,
+			Description: postBody(p),
+		}
+		if p.PublishedAt.Valid {
+			item.PubDate = fromTimestamp(p.PublishedAt.Time).Format(time.RFC1123Z)
+		}
+		doc.Channel.Items = append(doc.Channel.Items, item)
+	}
+	return doc
+}
+
+type publishedAtom struct {
+	XMLName   xml.Name             `xml:"http://www.w3.org/2005/Atom feed"`
+	ID        string               `xml:"id"`
+	Title     string               `xml:"title"`
+	Updated   string               `xml:"updated"`
+	Links     []publishedAtomLink  `xml:"link"`
+	Author    publishedAtomAuthor  `xml:"author"`
+	Generator string               `xml:"generator"`
+	Entries   []publishedAtomEntry `xml:"entry"`
+}
+
+type publishedAtomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type publishedAtomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type publishedAtomEntry struct {
+	ID        string                 `xml:"id"`
+	Title     string                 `xml:"title"`
+	Updated   string                 `xml:"updated"`
+	Published string                 `xml:"published,omitempty"`
+	Link      publishedAtomLink      `xml:"link"`
+	Category  *publishedAtomCategory `xml:"category"`
+	Content   *publishedAtomContent  `xml:"content"`
+}
+
+type publishedAtomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type publishedAtomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+func atomDocument(user database.User, posts []database.GetPostsForUserRow, selfURL string, modified time.Time) publishedAtom {
+	doc := publishedAtom{
+		ID:        "urn:uuid:" + user.ID.String(),
+		Title:     "gator: " + user.Name,
+		Updated:   modified.Format(time.RFC3339),
+		Links:     []publishedAtomLink{{Href: selfURL, Rel: "self", Type: "application/atom+xml"}},
+		Author:    publishedAtomAuthor{Name: user.Name},
+		Generator: "gator",
+	}
+	for _, p := range posts {
+		entry := publishedAtomEntry{
+			ID:      postGUID(p),
+			Title:   p.Title,
+			Updated: fromTimestamp(p.UpdatedAt).Format(time.RFC3339),
+			Link:    publishedAtomLink{Href: p.Url, Rel: "alternate"},
+		}
+		if p.PublishedAt.Valid {
+			entry.Published = fromTimestamp(p.PublishedAt.Time).Format(time.RFC3339)
+		}
+		if p.FeedName != "" {
+			entry.Category = &publishedAtomCategory{Term: p.FeedName}
+		}
+		if body := postBody(p); body != "" {
+			entry.Content = &publishedAtomContent{Type: "html", Body: body}
+		}
+		doc.Entries = append(doc.Entries, entry)
+	}
+	return doc
+}
+
+func handlerPublish(s *state, cmd command, user database.User) error {
+	fs := newFlagSet("publish")
+	format := fs.String("format", "rss", "rss or atom")
+	limit := fs.Int("limit", publishLimit, "number of posts to include")
+	out := fs.String("out", "", "write the feed to this file instead of stdout")
+	showURL := fs.Bool("url", false, "print the secret URLs `serve` publishes the feed at")
+	rotate := fs.Bool("rotate", false, "replace the secret URLs; old ones stop working")
+	baseURL := fs.String("base-url", "http://localhost:8080", "base URL `serve` is reachable at")
+	args, err := parseArgs(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return errors.New("publish takes no arguments")
+	}
+	if *format != "rss" && *format != "atom" {
+		return errors.New("publish --format must be rss or atom")
+	}
+	if *limit <= 0 {
+		return errors.New("publish --limit must be a positive integer")
+	}
+
+	ctx := context.Background()
+	secret, err := publishedFeedSecret(ctx, s, user, *rotate)
+	if err != nil {
+		return err
+	}
+	if *showURL || *rotate {
+		fmt.Println("RSS: ", publishedFeedURL(*baseURL, secret, "rss"))
+		fmt.Println("Atom:", publishedFeedURL(*baseURL, secret, "atom"))
+		return nil
+	}
+
+	doc, err := publishedFeed(ctx, s, user, *format, publishedFeedURL(*baseURL, secret, *format), int32(*limit))
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		_, err = os.Stdout.Write(doc)
+		return err
+	}
+	return os.WriteFile(*out, doc, 0o644)
+}
+
+// publishedFeed serves a user's feed at its secret URL. Feed readers can't
+// send API tokens, so the secret stands in for one.
+func (a *apiServer) publishedFeed(w http.ResponseWriter, r *http.Request) {
+	format, ok := publishFormats[r.PathValue("file")]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	secret := r.PathValue("secret")
+	user, err := a.s.db.GetUserByPublishSecret(r.Context(), secret)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.NotFound(w, r)
+			return
+		}
+		internalError(w, err)
+		return
+	}
+
+	selfURL := publishedFeedURL(requestScheme(r)+"://"+r.Host, secret, format)
+
+	doc, err := publishedFeed(r.Context(), a.s, user, format, selfURL, publishLimit)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	sum := sha256.Sum256(doc)
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	w.Header().Set("Content-Type", "application/"+format+"+xml; charset=utf-8")
+	// handles If-None-Match. No Last-Modified: the newest post's time doesn't
+	// change when posts drop out after an unfollow or mute, the hash does.
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(doc))
+}
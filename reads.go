@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"gator/internal/database"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// readTarget is what markread/markunread apply to: a list of post IDs, every
+// post of one feed, or every post older than a cutoff.
+type readTarget struct {
+	postIDs []uuid.UUID
+	feed    *database.Feed
+	cutoff  time.Time
+}
+
+func parseReadTarget(s *state, cmd command) (readTarget, error) {
+	usage := fmt.Errorf("%s requires post ids, --feed <url>, or --older-than <duration>", cmd.name)
+
+	fs := newFlagSet(cmd.name)
+	feedURL := fs.String("feed", "", "apply to every post of this feed")
+	olderThan := fs.Duration("older-than", 0, "apply to every post older than this (e.g. 72h)")
+	args, err := parseArgs(fs, cmd.args)
+	if err != nil {
+		return readTarget{}, err
+	}
+
+	forms := 0
+	for _, used := range []bool{len(args) > 0, *feedURL != "", *olderThan > 0} {
+		if used {
+			forms++
+		}
+	}
+	if forms != 1 {
+		return readTarget{}, usage
+	}
+
+	var target readTarget
+	switch {
+	case *feedURL != "":
+		feed, err := s.db.GetFeedByURL(context.Background(), *feedURL)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return readTarget{}, fmt.Errorf("feed not found: %s", *feedURL)
+			}
+			return readTarget{}, err
+		}
+		target.feed = &feed
+	case *olderThan > 0:
+		target.cutoff = time.Now().Add(-*olderThan)
+	default:
+		for _, arg := range args {
+			id, err := uuid.Parse(arg)
+			if err != nil {
+				return readTarget{}, fmt.Errorf("invalid post id: %s", arg)
+			}
+			target.postIDs = append(target.postIDs, id)
+		}
+	}
+	return target, nil
+}
+
+func handlerMarkRead(s *state, cmd command, user database.User) error {
+	target, err := parseReadTarget(s, cmd)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var n int64
+	switch {
+	case target.feed != nil:
+		n, err = s.db.MarkFeedPostsRead(context.Background(), database.MarkFeedPostsReadParams{
+			UserID: user.ID,
+			ReadAt: now,
+			FeedID: target.feed.ID,
+		})
+	case !target.cutoff.IsZero():
+		n, err = s.db.MarkPostsReadBefore(context.Background(), database.MarkPostsReadBeforeParams{
+			UserID: user.ID,
+			ReadAt: now,
+			Cutoff: target.cutoff,
+		})
+	default:
+		n, err = s.db.MarkPostsRead(context.Background(), database.MarkPostsReadParams{
+			UserID:  user.ID,
+			ReadAt:  now,
+			PostIds: target.postIDs,
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("marked %d posts as read\n", n)
+	return nil
+}
+
+func handlerMarkUnread(s *state, cmd command, user database.User) error {
+	target, err := parseReadTarget(s, cmd)
+	if err != nil {
+		return err
+	}
+
+	var n int64
+	switch {
+	case target.feed != nil:
+		n, err = s.db.MarkFeedPostsUnread(context.Background(), database.MarkFeedPostsUnreadParams{
+			UserID: user.ID,
+			FeedID: target.feed.ID,
+		})
+	case !target.cutoff.IsZero():
+		n, err = s.db.MarkPostsUnreadBefore(context.Background(), database.MarkPostsUnreadBeforeParams{
+			UserID: user.ID,
+			Cutoff: target.cutoff,
+		})
+	default:
+		n, err = s.db.MarkPostsUnread(context.Background(), database.MarkPostsUnreadParams{
+			UserID:  user.ID,
+			PostIds: target.postIDs,
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("marked %d posts as unread\n", n)
+	return nil
+}
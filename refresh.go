@@ -0,0 +1,96 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// refreshHints are the polling hints a feed declares about itself.
+type refreshHints struct {
+	// Interval is the shortest time between polls the feed asks for, taken
+	// from <ttl> or sy:updatePeriod/sy:updateFrequency. Zero means no hint.
+	Interval  time.Duration
+	SkipHours map[int]bool // UTC hours during which the feed should not be polled
+	SkipDays  map[time.Weekday]bool
+}
+
+var syndicationPeriods = map[string]time.Duration{
+	"hourly":  time.Hour,
+	"daily":   24 * time.Hour,
+	"weekly":  7 * 24 * time.Hour,
+	"monthly": 30 * 24 * time.Hour,
+	"yearly":  365 * 24 * time.Hour,
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// feedRefreshHints extracts the refresh hints from a parsed feed. Values
+// that don't parse are ignored rather than failing the scrape.
+func feedRefreshHints(feed *RSSFeed) refreshHints {
+	ch := feed.Channel
+	hints := refreshHints{
+		SkipHours: make(map[int]bool),
+		SkipDays:  make(map[time.Weekday]bool),
+	}
+
+	// <ttl> is in minutes
+	if ttl, err := strconv.Atoi(strings.TrimSpace(ch.TTL)); err == nil && ttl > 0 {
+		hints.Interval = time.Duration(ttl) * time.Minute
+	}
+
+	// sy:updateFrequency defaults to 1 per sy:updatePeriod
+	if period, ok := syndicationPeriods[strings.ToLower(strings.TrimSpace(ch.UpdatePeriod))]; ok {
+		freq := 1
+		if n, err := strconv.Atoi(strings.TrimSpace(ch.UpdateFrequency)); err == nil && n > 0 {
+			freq = n
+		}
+		// when both are present, honor the more conservative one
+		if d := period / time.Duration(freq); d > hints.Interval {
+			hints.Interval = d
+		}
+	}
+
+	for _, h := range ch.SkipHours {
+		// RSS 2.0 allows 0-23; some feeds use 24 for midnight
+		if n, err := strconv.Atoi(strings.TrimSpace(h)); err == nil && n >= 0 && n <= 24 {
+			hints.SkipHours[n%24] = true
+		}
+	}
+	for _, d := range ch.SkipDays {
+		if wd, ok := weekdays[strings.ToLower(strings.TrimSpace(d))]; ok {
+			hints.SkipDays[wd] = true
+		}
+	}
+
+	return hints
+}
+
+// nextFetchAt returns the earliest time the feed should be polled again,
+// interval after now and outside any skipHours/skipDays window.
+func (h refreshHints) nextFetchAt(now time.Time, interval time.Duration) time.Time {
+	next := now.Add(interval)
+	if len(h.SkipHours) == 0 && len(h.SkipDays) == 0 {
+		return next
+	}
+
+	// skip windows are whole UTC hours; give up after a week in case the
+	// feed skips every hour
+	t := next
+	for i := 0; i < 7*24; i++ {
+		utc := t.UTC()
+		if !h.SkipHours[utc.Hour()] && !h.SkipDays[utc.Weekday()] {
+			return t
+		}
+		t = utc.Truncate(time.Hour).Add(time.Hour).In(next.Location())
+	}
+	return next
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// outputFormat selects how listing commands print their results.
+type outputFormat string
+
+const (
+	outputText  outputFormat = "" // each command's own human-oriented output
+	outputJSON  outputFormat = "json"
+	outputCSV   outputFormat = "csv"
+	outputTSV   outputFormat = "tsv"
+	outputTable outputFormat = "table"
+)
+
+func parseOutputFormat(value string) (outputFormat, error) {
+	switch f := outputFormat(strings.ToLower(value)); f {
+	case outputJSON, outputCSV, outputTSV, outputTable:
+		return f, nil
+	case "text":
+		return outputText, nil
+	}
+	return "", fmt.Errorf("--output must be json, csv, tsv or table, not %q", value)
+}
+
+// extractOutputFlag removes the global --output option from args, wherever
+// it appears, so command flag sets never see it.
+func extractOutputFlag(args []string) (outputFormat, []string, error) {
+	format := outputText
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != "output" {
+			rest = append(rest, arg)
+			continue
+		}
+		if !hasValue {
+			if i+1 == len(args) {
+				return "", nil, fmt.Errorf("%s requires a format", arg)
+			}
+			i++
+			value = args[i]
+		}
+
+		f, err := parseOutputFormat(value)
+		if err != nil {
+			return "", nil, err
+		}
+		format = f
+	}
+	return format, rest, nil
+}
+
+// listing is the structured form of a listing command's results: one
+// record per item, each with the same fields in the same order. Field names
+// are part of the output contract for scripts, so don't rename them.
+type listing struct {
+	fields  []string
+	records [][]any
+}
+
+func newListing(fields ...string) *listing {
+	return &listing{fields: fields}
+}
+
+// add appends a record with one value per field. Nullable database types
+// become nil (null in JSON, empty elsewhere) or their plain value, and
+// times are taken to come from TIMESTAMP columns.
+func (l *listing) add(values ...any) {
+	if len(values) != len(l.fields) {
+		panic(fmt.Sprintf("listing: %d values for %d fields", len(values), len(l.fields)))
+	}
+	record := make([]any, len(values))
+	for i, v := range values {
+		record[i] = plainValue(v)
+	}
+	l.records = append(l.records, record)
+}
+
+// addField appends a field to every record, with value(i) for record i.
+func (l *listing) addField(name string, value func(i int) any) {
+	l.fields = append(l.fields, name)
+	for i := range l.records {
+		l.records[i] = append(l.records[i], plainValue(value(i)))
+	}
+}
+
+func plainValue(v any) any {
+	switch v := v.(type) {
+	case sql.NullString:
+		if v.Valid {
+			return v.String
+		}
+		return nil
+	case sql.NullTime:
+		if v.Valid {
+			return fromTimestamp(v.Time)
+		}
+		return nil
+	case time.Time:
+		return fromTimestamp(v)
+	case sql.NullInt32:
+		if v.Valid {
+			return v.Int32
+		}
+		return nil
+	case sql.NullInt64:
+		if v.Valid {
+			return v.Int64
+		}
+		return nil
+	case uuid.NullUUID:
+		if v.Valid {
+			return v.UUID
+		}
+		return nil
+	}
+	return v
+}
+
+// render prints l in the format chosen with --output. In text mode it calls
+// text instead, which prints the command's usual output.
+func render(s *state, l *listing, text func()) error {
+	switch s.output {
+	case outputJSON:
+		return renderJSON(l)
+	case outputCSV:
+		return renderCSV(l)
+	case outputTSV:
+		return renderTSV(l)
+	case outputTable:
+		return renderTable(l)
+	}
+	text()
+	return nil
+}
+
+func renderJSON(l *listing) error {
+	// build each object by hand so fields keep the listing's order
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i, record := range l.records {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteByte('{')
+		for j, v := range record {
+			if j > 0 {
+				buf.WriteByte(',')
+			}
+			name, _ := json.Marshal(l.fields[j])
+			value, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			buf.Write(name)
+			buf.WriteByte(':')
+			buf.Write(value)
+		}
+		buf.WriteByte('}')
+	}
+	buf.WriteByte(']')
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, buf.Bytes(), "", "  "); err != nil {
+		return err
+	}
+	out.WriteByte('\n')
+	_, err := out.WriteTo(os.Stdout)
+	return err
+}
+
+func renderCSV(l *listing) error {
+	w := csv.NewWriter(os.Stdout)
+	if err := w.Write(l.fields); err != nil {
+		return err
+	}
+	for _, record := range l.records {
+		row := make([]string, len(record))
+		for i, v := range record {
+			row[i] = formatValue(v)
+		}
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// tsvEscaper escapes the characters that would break a TSV row, the same
+// way Postgres COPY does.
+var tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+func renderTSV(l *listing) error {
+	var buf bytes.Buffer
+	buf.WriteString(strings.Join(l.fields, "\t"))
+	buf.WriteByte('\n')
+	for _, record := range l.records {
+		for i, v := range record {
+			if i > 0 {
+				buf.WriteByte('\t')
+			}
+			buf.WriteString(tsvEscaper.Replace(formatValue(v)))
+		}
+		buf.WriteByte('\n')
+	}
+	_, err := buf.WriteTo(os.Stdout)
+	return err
+}
+
+// tableCellWidth caps table cells so long descriptions don't swamp the rest.
+const tableCellWidth = 60
+
+func renderTable(l *listing) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.ToUpper(strings.Join(l.fields, "\t")))
+	for _, record := range l.records {
+		cells := make([]string, len(record))
+		for i, v := range record {
+			cell := strings.Join(strings.Fields(formatValue(v)), " ")
+			if utf8.RuneCountInString(cell) > tableCellWidth {
+				cell = string([]rune(cell)[:tableCellWidth-1]) + "…"
+			}
+			cells[i] = cell
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+	return w.Flush()
+}
+
+// formatValue renders a record value as text for csv, tsv and table.
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case []string:
+		return strings.Join(v, ",")
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gator/internal/database"
+	"time"
+)
+
+// pruneInterval is how often `agg --prune` applies the retention policy.
+const pruneInterval = time.Hour
+
+// retentionPolicy decides which posts prune removes. A zero field disables
+// that rule.
+type retentionPolicy struct {
+	MaxAge          time.Duration
+	MaxPostsPerFeed int
+}
+
+func (p retentionPolicy) enabled() bool {
+	return p.MaxAge > 0 || p.MaxPostsPerFeed > 0
+}
+
+func (p retentionPolicy) String() string {
+	var rules []string
+	if p.MaxAge > 0 {
+		rules = append(rules, fmt.Sprintf("older than %s", p.MaxAge))
+	}
+	if p.MaxPostsPerFeed > 0 {
+		rules = append(rules, fmt.Sprintf("beyond the newest %d per feed", p.MaxPostsPerFeed))
+	}
+	if len(rules) == 0 {
+		return "nothing"
+	}
+	if len(rules) == 1 {
+		return "posts " + rules[0]
+	}
+	return "posts " + rules[0] + " or " + rules[1]
+}
+
+// configuredRetention reads the retention policy from the config file.
+func configuredRetention(s *state) (retentionPolicy, error) {
+	maxAge, maxPosts, err := s.cfg.RetentionPolicy()
+	if err != nil {
+		return retentionPolicy{}, err
+	}
+	return retentionPolicy{MaxAge: maxAge, MaxPostsPerFeed: maxPosts}, nil
+}
+
+// prunedURLMargin is how long past the retention window a pruned post's
+// URL is remembered. Feeds rarely keep listing an entry that long.
+const prunedURLMargin = 30 * 24 * time.Hour
+
+// prunePosts deletes the posts policy doesn't keep and returns how many
+// were removed. It also forgets URLs pruned more than the retention window
+// plus prunedURLMargin ago, so pruned_posts doesn't grow forever.
+func prunePosts(s *state, policy retentionPolicy) (int64, error) {
+	n, err := s.db.DeletePrunablePosts(context.Background(), database.DeletePrunablePostsParams{
+		PruneByAge: policy.MaxAge > 0,
+		Cutoff:     time.Now().Add(-policy.MaxAge),
+		MaxPosts:   int32(policy.MaxPostsPerFeed),
+		PrunedAt:   time.Now(),
+	})
+	if err != nil {
+		return 0, err
+	}
+	if _, err := s.db.DeletePrunedPostsBefore(context.Background(), time.Now().Add(-policy.MaxAge-prunedURLMargin)); err != nil {
+		return n, fmt.Errorf("forget pruned urls: %w", err)
+	}
+	return n, nil
+}
+
+func handlerPrune(s *state, cmd command) error {
+	policy, err := configuredRetention(s)
+	if err != nil {
+		return err
+	}
+
+	fs := newFlagSet("prune")
+	dryRun := fs.Bool("dry-run", false, "only show what would be removed")
+	fs.DurationVar(&policy.MaxAge, "max-age", policy.MaxAge, "remove posts older than this")
+	fs.IntVar(&policy.MaxPostsPerFeed, "max-posts", policy.MaxPostsPerFeed, "keep at most this many posts per feed")
+	args, err := parseArgs(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return errors.New("prune takes no arguments")
+	}
+	if policy.MaxAge < 0 || policy.MaxPostsPerFeed < 0 {
+		return errors.New("prune --max-age and --max-posts must not be negative")
+	}
+	if !policy.enabled() {
+		return errors.New("no retention policy: set retention_max_age or retention_max_posts_per_feed, or pass --max-age/--max-posts")
+	}
+
+	if !*dryRun {
+		n, err := prunePosts(s, policy)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("pruned %d posts (%s)\n", n, policy)
+		return nil
+	}
+
+	counts, err := s.db.CountPrunablePosts(context.Background(), database.CountPrunablePostsParams{
+		PruneByAge: policy.MaxAge > 0,
+		Cutoff:     time.Now().Add(-policy.MaxAge),
+		MaxPosts:   int32(policy.MaxPostsPerFeed),
+	})
+	if err != nil {
+		return err
+	}
+
+	var total int64
+	for _, c := range counts {
+		fmt.Printf("* %s: %d posts\n", c.Name, c.Prunable)
+		fmt.Printf("  %s\n", c.Url)
+		total += c.Prunable
+	}
+	fmt.Printf("dry run: would prune %d posts (%s)\n", total, policy)
+	return nil
+}
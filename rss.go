@@ -7,16 +7,38 @@ import (
 	"html"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 )
 
 type RSSFeed struct {
 	Channel struct {
+		// must come before Link: a plain `xml:"link"` field would also
+		// match <atom:link> elements
+		AtomLinks []AtomLink `xml:"http://www.w3.org/2005/Atom link"`
+
 		Title       string    `xml:"title"`
 		Link        string    `xml:"link"`
 		Description string    `xml:"description"`
 		Item        []RSSItem `xml:"item"`
+
+		// refresh hints, see refresh.go
+		TTL             string   `xml:"ttl"`
+		SkipHours       []string `xml:"skipHours>hour"`
+		SkipDays        []string `xml:"skipDays>day"`
+		UpdatePeriod    string   `xml:"http://purl.org/rss/1.0/modules/syndication/ updatePeriod"`
+		UpdateFrequency string   `xml:"http://purl.org/rss/1.0/modules/syndication/ updateFrequency"`
 	} `xml:"channel"`
+
+	// Link headers from the HTTP response, which can advertise a WebSub
+	// hub as well
+	LinkHeaders []string `xml:"-"`
+}
+
+type AtomLink struct {
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
 }
 
 type RSSItem struct {
@@ -24,13 +46,47 @@ type RSSItem struct {
 	Link        string `xml:"link"`
 	Description string `xml:"description"`
 	PubDate     string `xml:"pubDate"`
+	// Content is the full body from content:encoded, when the feed has one.
+	Content string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	// Author is usually an email address; feeds more often name the
+	// author in dc:creator.
+	Author     string   `xml:"author"`
+	Creator    string   `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Categories []string `xml:"category"`
 }
 
-func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
+// categories returns the item's categories, trimmed and without blanks.
+func (item RSSItem) categories() []string {
+	categories := []string{}
+	for _, c := range item.Categories {
+		if c = strings.TrimSpace(c); c != "" {
+			categories = append(categories, c)
+		}
+	}
+	return categories
+}
+
+// author returns who wrote the item, or "" if the feed doesn't say.
+func (item RSSItem) author() string {
+	if item.Creator != "" {
+		return strings.TrimSpace(item.Creator)
+	}
+	return strings.TrimSpace(item.Author)
+}
+
+// fetchStats describes the HTTP side of a fetch, for the fetch log.
+type fetchStats struct {
+	StatusCode int // zero if no response was received
+	Bytes      int64
+}
+
+func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, fetchStats, error) {
+	var stats fetchStats
+
 	// build request with context
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feedURL, nil)
 	if err != nil {
-		return nil, fmt.Errorf("create request: %w", err)
+		return nil, stats, fmt.Errorf("create request: %w", err)
 	}
 	req.Header.Set("User-Agent", "gator")
 
@@ -38,16 +94,37 @@ func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
 	client := &http.Client{Timeout: 10 * time.Second}
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("do request: %w", err)
+		return nil, stats, fmt.Errorf("do request: %w", err)
 	}
 	defer resp.Body.Close()
+	stats.StatusCode = resp.StatusCode
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		statusErr := &httpStatusError{StatusCode: resp.StatusCode}
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+			statusErr.RetryAfter, _ = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		}
+		return nil, stats, statusErr
+	}
 
 	// read body
 	body, err := io.ReadAll(resp.Body)
+	stats.Bytes = int64(len(body))
+	if err != nil {
+		return nil, stats, fmt.Errorf("read body: %w", err)
+	}
+
+	feed, err := parseFeed(body)
 	if err != nil {
-		return nil, fmt.Errorf("read body: %w", err)
+		return nil, stats, err
 	}
+	feed.LinkHeaders = resp.Header.Values("Link")
 
+	return feed, stats, nil
+}
+
+// parseFeed decodes an RSS document, as fetched or as pushed by a WebSub hub.
+func parseFeed(body []byte) (*RSSFeed, error) {
 	// unmarshal xml
 	var feed RSSFeed
 	if err := xml.Unmarshal(body, &feed); err != nil {
@@ -67,6 +144,45 @@ func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
 	return &feed, nil
 }
 
+// httpStatusError is returned by fetchFeed when the server answers with a
+// non-2xx status.
+type httpStatusError struct {
+	StatusCode int
+	RetryAfter time.Duration // zero unless the server sent a usable Retry-After
+}
+
+func (e *httpStatusError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("unexpected status %d (retry after %s)", e.StatusCode, e.RetryAfter)
+	}
+	return fmt.Sprintf("unexpected status %d", e.StatusCode)
+}
+
+// parseRetryAfter understands both forms of the Retry-After header: a
+// number of seconds, or an HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+
+	return 0, false
+}
+
 func parsePubDate(pubDate string) (time.Time, bool) {
 	if pubDate == "" {
 		return time.Time{}, false
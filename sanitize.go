@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"gator/internal/database"
+	"gator/internal/markup"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// resanitizeBatchSize is how many posts resanitize loads at a time.
+const resanitizeBatchSize = 500
+
+// sanitizeHTML cleans feed-supplied HTML before it is stored, resolving
+// relative links against the post's link. Nothing left means NULL.
+func sanitizeHTML(raw sql.NullString, link string) sql.NullString {
+	if !raw.Valid {
+		return raw
+	}
+	clean := markup.Sanitize(raw.String, link)
+	return sql.NullString{String: clean, Valid: strings.TrimSpace(clean) != ""}
+}
+
+// sanitizePosts re-runs the sanitizer over the original content of every
+// post, or only of those never sanitized, and returns how many it updated.
+func sanitizePosts(ctx context.Context, s *state, unsanitizedOnly bool) (int, error) {
+	var after uuid.UUID
+	updated := 0
+	for {
+		posts, err := s.db.GetPostsToSanitize(ctx, database.GetPostsToSanitizeParams{
+			ID:              after,
+			UnsanitizedOnly: unsanitizedOnly,
+			Limit:           resanitizeBatchSize,
+		})
+		if err != nil {
+			return updated, err
+		}
+		for _, p := range posts {
+			err := s.db.UpdatePostContent(ctx, database.UpdatePostContentParams{
+				ID:          p.ID,
+				Description: sanitizeHTML(p.RawDescription, p.Url),
+				Content:     sanitizeHTML(p.RawContent, p.Url),
+				UpdatedAt:   time.Now(),
+			})
+			if err != nil {
+				return updated, err
+			}
+			updated++
+		}
+		if len(posts) < resanitizeBatchSize {
+			return updated, nil
+		}
+		after = posts[len(posts)-1].ID
+	}
+}
+
+// handlerResanitize re-runs the sanitizer over the original content of
+// every post, e.g. after the allowlist changes. With --unsanitized it only
+// does the posts stored before sanitizing was added, which migration 022
+// flags.
+func handlerResanitize(s *state, cmd command) error {
+	fs := newFlagSet("resanitize")
+	unsanitized := fs.Bool("unsanitized", false, "only sanitize posts stored before sanitizing was added")
+	args, err := parseArgs(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return errors.New("resanitize takes no arguments")
+	}
+
+	updated, err := sanitizePosts(context.Background(), s, *unsanitized)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("resanitized %d posts\n", updated)
+	return nil
+}
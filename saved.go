@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"gator/internal/database"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+func handlerSave(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 1 {
+		return errors.New("save requires a post id")
+	}
+	postID, err := uuid.Parse(cmd.args[0])
+	if err != nil {
+		return fmt.Errorf("invalid post id: %s", cmd.args[0])
+	}
+
+	saved, err := s.db.SavePost(context.Background(), database.SavePostParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UserID:    user.ID,
+		PostID:    postID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("post not found: %s", postID)
+		}
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return errors.New("post is already saved")
+		}
+		return err
+	}
+
+	fmt.Printf("saved %s\n", saved.Title)
+	return nil
+}
+
+func handlerUnsave(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 1 {
+		return errors.New("unsave requires a post id")
+	}
+	id, err := uuid.Parse(cmd.args[0])
+	if err != nil {
+		return fmt.Errorf("invalid post id: %s", cmd.args[0])
+	}
+
+	n, err := s.db.DeleteSavedPost(context.Background(), database.DeleteSavedPostParams{
+		UserID: user.ID,
+		ID:     id,
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("post is not saved: %s", id)
+	}
+
+	fmt.Println("post unsaved")
+	return nil
+}
+
+func handlerSaved(s *state, cmd command, user database.User) error {
+	limit, err := parseLimit("saved", cmd.args)
+	if err != nil {
+		return err
+	}
+
+	saved, err := s.db.GetSavedPostsForUser(context.Background(), database.GetSavedPostsForUserParams{
+		UserID: user.ID,
+		Limit:  limit,
+	})
+	if err != nil {
+		return err
+	}
+
+	views := make([]postView, len(saved))
+	for i, p := range saved {
+		// the original post may have been pruned; its saved copy can still
+		// be unsaved by its own ID
+		id := p.ID
+		if p.PostID.Valid {
+			id = p.PostID.UUID
+		}
+		views[i] = postView{
+			ID:          id,
+			FeedName:    p.FeedName,
+			Title:       p.Title,
+			Url:         p.Url,
+			Description: p.Description,
+			PublishedAt: p.PublishedAt,
+		}
+	}
+	return render(s, postListing(views), func() { printPosts(views) })
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"gator/internal/database"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// publishHistorySize is how many recent posts the scheduler looks at when
+// learning how often a feed publishes.
+const publishHistorySize = 20
+
+// scheduler decides when agg should poll each feed again. It learns a
+// feed's publishing rhythm from posts.published_at and polls about as often
+// as the feed publishes, within [minInterval, maxInterval].
+type scheduler struct {
+	db          *database.Queries
+	minInterval time.Duration
+	maxInterval time.Duration
+}
+
+func newScheduler(db *database.Queries, minInterval, maxInterval time.Duration) *scheduler {
+	return &scheduler{
+		db:          db,
+		minInterval: minInterval,
+		maxInterval: maxInterval,
+	}
+}
+
+// reschedule recomputes feed's poll interval after a scrape and stores
+// when it is next due. A refresh_interval set by the feed's owner wins over
+// everything else, including the feed's skipHours/skipDays.
+func (sc *scheduler) reschedule(ctx context.Context, feed database.Feed, hints refreshHints) (time.Duration, error) {
+	var interval time.Duration
+	var next time.Time
+	if feed.RefreshInterval.Valid {
+		interval = time.Duration(feed.RefreshInterval.Int32) * time.Second
+		next = time.Now().Add(interval)
+	} else {
+		gap, err := sc.publishGap(ctx, feed.ID)
+		if err != nil {
+			return 0, err
+		}
+		interval = sc.interval(gap, hints)
+
+		// feeds pushed to us over WebSub only need an occasional safety poll
+		pushed, err := sc.db.HasActiveWebSubSubscription(ctx, database.HasActiveWebSubSubscriptionParams{
+			FeedID: feed.ID,
+			Now:    time.Now(),
+		})
+		if err != nil {
+			return 0, err
+		}
+		if pushed {
+			interval = sc.maxInterval
+		}
+
+		next = hints.nextFetchAt(time.Now(), interval)
+	}
+
+	err := sc.db.SetFeedSchedule(ctx, database.SetFeedScheduleParams{
+		ID:           feed.ID,
+		PollInterval: sql.NullInt32{Int32: int32(interval / time.Second), Valid: true},
+		NextFetchAt:  sql.NullTime{Time: next, Valid: true},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return interval, nil
+}
+
+// interval combines the observed publish gap with the feed's own hints.
+// Feeds asking to be polled less often than we'd like get their way, but
+// everything stays within the configured bounds.
+func (sc *scheduler) interval(gap time.Duration, hints refreshHints) time.Duration {
+	interval := gap
+	if hints.Interval > interval {
+		interval = hints.Interval
+	}
+	return min(max(interval, sc.minInterval), sc.maxInterval)
+}
+
+// publishGap returns the median time between a feed's recent posts, or 0
+// when there isn't enough history to tell.
+func (sc *scheduler) publishGap(ctx context.Context, feedID uuid.UUID) (time.Duration, error) {
+	times, err := sc.db.GetFeedPublishTimes(ctx, database.GetFeedPublishTimesParams{
+		FeedID: feedID,
+		Limit:  publishHistorySize,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return medianGap(times), nil
+}
+
+// medianGap expects times newest first, as GetFeedPublishTimes returns them.
+func medianGap(times []sql.NullTime) time.Duration {
+	var gaps []time.Duration
+	for i := 1; i < len(times); i++ {
+		if !times[i-1].Valid || !times[i].Valid {
+			continue
+		}
+		if gap := times[i-1].Time.Sub(times[i].Time); gap > 0 {
+			gaps = append(gaps, gap)
+		}
+	}
+	if len(gaps) == 0 {
+		return 0
+	}
+
+	slices.Sort(gaps)
+	return gaps[len(gaps)/2]
+}
+
+// untilNextDue returns how long agg can sleep before some feed is due,
+// capped at idle.
+func (sc *scheduler) untilNextDue(ctx context.Context, idle time.Duration) (time.Duration, error) {
+	next, err := sc.db.GetEarliestNextFetch(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return idle, nil
+		}
+		return 0, err
+	}
+	if !next.Valid {
+		// a feed that has never been fetched
+		return 0, nil
+	}
+	return min(max(time.Until(fromTimestamp(next.Time)), 0), idle), nil
+}
+
+// fromTimestamp converts a TIMESTAMP column read back through lib/pq to
+// local time. Those columns hold the local wall-clock time we wrote, but
+// come back labelled UTC.
+func fromTimestamp(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.Local)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"gator/internal/database"
+	"strings"
+)
+
+func handlerSearch(s *state, cmd command, user database.User) error {
+	fs := newFlagSet("search")
+	all := fs.Bool("all", false, "search every feed, not just the ones you follow")
+	limit := fs.Int("limit", 10, "maximum number of results")
+	args, err := parseArgs(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	query := strings.TrimSpace(strings.Join(args, " "))
+	if query == "" {
+		return errors.New("search requires a query")
+	}
+	if *limit <= 0 {
+		return errors.New("search --limit must be a positive integer")
+	}
+
+	results, err := s.db.SearchPosts(context.Background(), database.SearchPostsParams{
+		Query:    query,
+		AllFeeds: *all,
+		UserID:   user.ID,
+		Limit:    int32(*limit),
+	})
+	if err != nil {
+		return err
+	}
+
+	views := make([]postView, len(results))
+	for i, r := range results {
+		// show the matching snippet in place of the description
+		views[i] = postView{
+			ID:          r.ID,
+			FeedName:    r.FeedName,
+			Title:       r.Title,
+			Url:         r.Url,
+			Description: sql.NullString{String: strings.Join(strings.Fields(r.Snippet), " "), Valid: r.Snippet != ""},
+			PublishedAt: r.PublishedAt,
+		}
+	}
+	l := postListing(views)
+	l.addField("rank", func(i int) any { return results[i].Rank })
+	return render(s, l, func() { printPosts(views) })
+}
@@ -0,0 +1,710 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gator/internal/database"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	apiDefaultLimit = 20
+	apiMaxLimit     = 100
+	apiMaxBodyBytes = 1 << 20
+)
+
+// apiServer serves gator's data as JSON for `serve`. Handlers use the same
+// queries as the CLI commands.
+type apiServer struct {
+	s *state
+}
+
+func newAPIServer(s *state) *apiServer {
+	return &apiServer{s: s}
+}
+
+func (a *apiServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/me", a.authenticated(a.getMe))
+	mux.HandleFunc("GET /api/users", a.authenticated(a.listUsers))
+	mux.HandleFunc("POST /api/users", a.authenticated(a.createUser))
+	mux.HandleFunc("GET /api/users/{name}", a.authenticated(a.getUser))
+	mux.HandleFunc("DELETE /api/users/{name}", a.authenticated(a.deleteUser))
+	mux.HandleFunc("GET /api/follows", a.authenticated(a.listFollows))
+	mux.HandleFunc("POST /api/follows", a.authenticated(a.createFollow))
+	mux.HandleFunc("DELETE /api/follows/{feed_id}", a.authenticated(a.deleteFollow))
+	mux.HandleFunc("GET /api/feeds", a.authenticated(a.listFeeds))
+	mux.HandleFunc("POST /api/feeds", a.authenticated(a.createFeed))
+	mux.HandleFunc("GET /api/feeds/{id}", a.authenticated(a.getFeed))
+	mux.HandleFunc("DELETE /api/feeds/{id}", a.authenticated(a.deleteFeed))
+	mux.HandleFunc("GET /api/feeds/{id}/posts", a.authenticated(a.listFeedPosts))
+	mux.HandleFunc("POST /api/feeds/{id}/posts", a.authenticated(a.createPost))
+	mux.HandleFunc("GET /api/posts", a.authenticated(a.browsePosts))
+	mux.HandleFunc("GET /api/posts/{id}", a.authenticated(a.getPost))
+	mux.HandleFunc("DELETE /api/posts/{id}", a.authenticated(a.deletePost))
+	mux.HandleFunc("GET /feeds/{secret}/{file}", a.publishedFeed)
+	newWebUI(a.s).register(mux)
+	return mux
+}
+
+// authenticated resolves the user from the request's bearer token, as
+// middlewareLoggedIn does from the config for commands.
+func (a *apiServer) authenticated(
+	handler func(w http.ResponseWriter, r *http.Request, user database.User),
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+			unauthorized(w, "missing bearer token")
+			return
+		}
+
+		user, err := userForAPIToken(r.Context(), a.s, strings.TrimSpace(token))
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				unauthorized(w, "invalid token")
+				return
+			}
+			internalError(w, err)
+			return
+		}
+
+		handler(w, r, user)
+	}
+}
+
+func handlerServe(s *state, cmd command) error {
+	fs := newFlagSet("serve")
+	addr := fs.String("addr", ":8080", "address to listen on")
+	args, err := parseArgs(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return errors.New("serve takes no arguments")
+	}
+
+	// the web reader shows post HTML as is, so posts stored before
+	// sanitizing was added must not be served until they are sanitized
+	n, err := sanitizePosts(context.Background(), s, true)
+	if err != nil {
+		return fmt.Errorf("sanitize stored posts: %w", err)
+	}
+	if n > 0 {
+		fmt.Printf("sanitized %d posts stored before sanitizing was added\n", n)
+	}
+
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           newAPIServer(s).handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	fmt.Printf("Serving the web reader and API on %s\n", *addr)
+	return srv.ListenAndServe()
+}
+
+type apiUser struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func apiUserFrom(u database.User) apiUser {
+	return apiUser{ID: u.ID, Name: u.Name, CreatedAt: fromTimestamp(u.CreatedAt)}
+}
+
+type apiFeed struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	URL       string    `json:"url"`
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func apiFeedFrom(f database.Feed) apiFeed {
+	return apiFeed{ID: f.ID, Name: f.Name, URL: f.Url, UserID: f.UserID, CreatedAt: fromTimestamp(f.CreatedAt)}
+}
+
+type apiFollow struct {
+	FeedID     uuid.UUID `json:"feed_id"`
+	FeedName   string    `json:"feed_name"`
+	Tags       []string  `json:"tags"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+type apiPost struct {
+	ID          uuid.UUID  `json:"id"`
+	FeedID      uuid.UUID  `json:"feed_id"`
+	FeedName    string     `json:"feed_name"`
+	Title       string     `json:"title"`
+	URL         string     `json:"url"`
+	Description *string    `json:"description"`
+	Content     *string    `json:"content"`
+	PublishedAt *time.Time `json:"published_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// apiPostFrom converts a post row. GetPostsForUserRow and GetPostsForFeedRow
+// select the same columns, so they convert to GetPostByIDRow directly.
+func apiPostFrom(p database.GetPostByIDRow) apiPost {
+	post := apiPost{
+		ID:        p.ID,
+		FeedID:    p.FeedID,
+		FeedName:  p.FeedName,
+		Title:     p.Title,
+		URL:       p.Url,
+		CreatedAt: fromTimestamp(p.CreatedAt),
+	}
+	if p.Description.Valid {
+		post.Description = &p.Description.String
+	}
+	if p.Content.Valid {
+		post.Content = &p.Content.String
+	}
+	if p.PublishedAt.Valid {
+		t := fromTimestamp(p.PublishedAt.Time)
+		post.PublishedAt = &t
+	}
+	return post
+}
+
+func (a *apiServer) listUsers(w http.ResponseWriter, r *http.Request, _ database.User) {
+	users, err := a.s.db.GetUsers(r.Context())
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	out := make([]apiUser, len(users))
+	for i, u := range users {
+		out[i] = apiUserFrom(u)
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (a *apiServer) createUser(w http.ResponseWriter, r *http.Request, _ database.User) {
+	var body struct {
+		Name string `json:"name"`
+	}
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	if body.Name == "" {
+		writeError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+
+	now := time.Now()
+	user, err := a.s.db.CreateUser(r.Context(), database.CreateUserParams{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Name:      body.Name,
+	})
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			writeError(w, http.StatusConflict, fmt.Sprintf("user %s already exists", body.Name))
+			return
+		}
+		internalError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, apiUserFrom(user))
+}
+
+func (a *apiServer) getMe(w http.ResponseWriter, r *http.Request, user database.User) {
+	writeJSON(w, http.StatusOK, apiUserFrom(user))
+}
+
+func (a *apiServer) getUser(w http.ResponseWriter, r *http.Request, _ database.User) {
+	other, err := a.s.db.GetUser(r.Context(), r.PathValue("name"))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, http.StatusNotFound, "user not found")
+			return
+		}
+		internalError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, apiUserFrom(other))
+}
+
+// deleteUser deletes the caller's own account; nobody else's.
+func (a *apiServer) deleteUser(w http.ResponseWriter, r *http.Request, user database.User) {
+	if r.PathValue("name") != user.Name {
+		writeError(w, http.StatusForbidden, "you can only delete your own user")
+		return
+	}
+	n, err := a.s.db.DeleteUser(r.Context(), r.PathValue("name"))
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	if n == 0 {
+		writeError(w, http.StatusNotFound, "user not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *apiServer) listFollows(w http.ResponseWriter, r *http.Request, user database.User) {
+	follows, err := a.s.db.GetFeedFollowsForUser(r.Context(), user.ID)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	out := make([]apiFollow, len(follows))
+	for i, f := range follows {
+		out[i] = apiFollow{FeedID: f.FeedID, FeedName: f.FeedName, Tags: f.Tags, FollowedAt: fromTimestamp(f.CreatedAt)}
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (a *apiServer) createFollow(w http.ResponseWriter, r *http.Request, user database.User) {
+	var body struct {
+		FeedURL string `json:"feed_url"`
+	}
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+
+	feed, err := a.s.db.GetFeedByURL(r.Context(), body.FeedURL)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("feed not found: %s", body.FeedURL))
+			return
+		}
+		internalError(w, err)
+		return
+	}
+
+	now := time.Now()
+	ff, err := a.s.db.CreateFeedFollow(r.Context(), database.CreateFeedFollowParams{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		UserID:    user.ID,
+		FeedID:    feed.ID,
+	})
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			writeError(w, http.StatusConflict, fmt.Sprintf("%s already follows %s", user.Name, feed.Name))
+			return
+		}
+		internalError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, apiFollow{FeedID: ff.FeedID, FeedName: ff.FeedName, FollowedAt: fromTimestamp(ff.CreatedAt)})
+}
+
+func (a *apiServer) deleteFollow(w http.ResponseWriter, r *http.Request, user database.User) {
+	feedID, err := uuid.Parse(r.PathValue("feed_id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "feed not found")
+		return
+	}
+
+	err = a.s.db.DeleteFeedFollow(r.Context(), database.DeleteFeedFollowParams{
+		UserID: user.ID,
+		FeedID: feedID,
+	})
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// browsePosts is `browse` over HTTP for the caller: the same filters and
+// cursors, given as query parameters. Unlike the command it doesn't mark
+// posts read.
+func (a *apiServer) browsePosts(w http.ResponseWriter, r *http.Request, user database.User) {
+	q := r.URL.Query()
+	limit, ok := queryLimit(w, r)
+	if !ok {
+		return
+	}
+	paging := pageOptions{before: q.Get("before"), after: q.Get("after"), page: 1}
+	if q.Has("page") {
+		page, err := strconv.Atoi(q.Get("page"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "page must be an integer")
+			return
+		}
+		paging.page = page
+	}
+	filters := postFilters{
+		unreadOnly: q.Get("unread") == "true",
+		feeds:      q["feed"],
+		tags:       q["tag"],
+		since:      q.Get("since"),
+		until:      q.Get("until"),
+		match:      q.Get("match"),
+		showMuted:  q.Get("show_muted") == "true",
+	}
+
+	params := newPostQuery(user.ID, limit)
+	if err := filters.apply(&params); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := paging.apply(&params); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	posts, err := params.fetch(r.Context(), a.s.db)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	if params.CursorDirection == "after" {
+		slices.Reverse(posts)
+	}
+
+	out := struct {
+		Posts []apiPost `json:"posts"`
+		Newer string    `json:"newer,omitempty"` // pass as ?after=
+		Older string    `json:"older,omitempty"` // pass as ?before=
+	}{Posts: make([]apiPost, len(posts))}
+	for i, p := range posts {
+		out.Posts[i] = apiPostFrom(database.GetPostByIDRow(p))
+	}
+	out.Newer, out.Older = pageCursors(posts, params)
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (a *apiServer) listFeeds(w http.ResponseWriter, r *http.Request, _ database.User) {
+	feeds, err := a.s.db.GetFeeds(r.Context())
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	out := make([]apiFeed, len(feeds))
+	for i, f := range feeds {
+		out[i] = apiFeed{ID: f.ID, Name: f.Name, URL: f.Url, UserID: f.UserID, CreatedAt: fromTimestamp(f.CreatedAt)}
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// createFeed adds a feed owned by the caller, who follows it, like addfeed.
+func (a *apiServer) createFeed(w http.ResponseWriter, r *http.Request, user database.User) {
+	var body struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	}
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	if body.Name == "" || body.URL == "" {
+		writeError(w, http.StatusBadRequest, "name and url are required")
+		return
+	}
+	now := time.Now()
+	feed, err := a.s.db.CreateFeed(r.Context(), database.CreateFeedParams{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Name:      body.Name,
+		Url:       body.URL,
+		UserID:    user.ID,
+	})
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			writeError(w, http.StatusConflict, fmt.Sprintf("feed url already exists: %s", body.URL))
+			return
+		}
+		internalError(w, err)
+		return
+	}
+
+	_, err = a.s.db.CreateFeedFollow(r.Context(), database.CreateFeedFollowParams{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		UserID:    user.ID,
+		FeedID:    feed.ID,
+	})
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, apiFeedFrom(feed))
+}
+
+func (a *apiServer) getFeed(w http.ResponseWriter, r *http.Request, _ database.User) {
+	feed, ok := a.feed(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, apiFeedFrom(feed))
+}
+
+// deleteFeed deletes a feed the caller added.
+func (a *apiServer) deleteFeed(w http.ResponseWriter, r *http.Request, user database.User) {
+	feed, ok := a.feed(w, r)
+	if !ok {
+		return
+	}
+	if feed.UserID != user.ID {
+		writeError(w, http.StatusForbidden, "only the user who added a feed can delete it")
+		return
+	}
+	n, err := a.s.db.DeleteFeed(r.Context(), feed.ID)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	if n == 0 {
+		writeError(w, http.StatusNotFound, "feed not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *apiServer) listFeedPosts(w http.ResponseWriter, r *http.Request, _ database.User) {
+	feed, ok := a.feed(w, r)
+	if !ok {
+		return
+	}
+	limit, ok := queryLimit(w, r)
+	if !ok {
+		return
+	}
+	page := 1
+	if v := r.URL.Query().Get("page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, "page must be at least 1")
+			return
+		}
+		page = n
+	}
+
+	posts, err := a.s.db.GetPostsForFeed(r.Context(), database.GetPostsForFeedParams{
+		FeedID: feed.ID,
+		Limit:  limit,
+		Offset: int32(page-1) * limit,
+	})
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	out := make([]apiPost, len(posts))
+	for i, p := range posts {
+		out[i] = apiPostFrom(database.GetPostByIDRow(p))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// createPost adds a post by hand to a feed the caller added. Its HTML is
+// sanitized like that of fetched posts.
+func (a *apiServer) createPost(w http.ResponseWriter, r *http.Request, user database.User) {
+	feed, ok := a.feed(w, r)
+	if !ok {
+		return
+	}
+	if feed.UserID != user.ID {
+		writeError(w, http.StatusForbidden, "only the user who added a feed can add posts to it")
+		return
+	}
+	var body struct {
+		Title       string     `json:"title"`
+		URL         string     `json:"url"`
+		Description string     `json:"description"`
+		Content     string     `json:"content"`
+		PublishedAt *time.Time `json:"published_at"`
+	}
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	if body.Title == "" || body.URL == "" {
+		writeError(w, http.StatusBadRequest, "title and url are required")
+		return
+	}
+
+	desc := sql.NullString{String: body.Description, Valid: body.Description != ""}
+	content := sql.NullString{String: body.Content, Valid: body.Content != ""}
+	var publishedAt sql.NullTime
+	if body.PublishedAt != nil {
+		publishedAt = sql.NullTime{Time: body.PublishedAt.Local(), Valid: true}
+	}
+
+	now := time.Now()
+	post, err := a.s.db.CreatePost(r.Context(), database.CreatePostParams{
+		ID:             uuid.New(),
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		Title:          body.Title,
+		Url:            body.URL,
+		Description:    sanitizeHTML(desc, body.URL),
+		PublishedAt:    publishedAt,
+		FeedID:         feed.ID,
+		Content:        sanitizeHTML(content, body.URL),
+		RawDescription: desc,
+		RawContent:     content,
+	})
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			writeError(w, http.StatusConflict, fmt.Sprintf("post url already exists: %s", body.URL))
+			return
+		}
+		internalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, apiPostFrom(database.GetPostByIDRow{
+		ID:          post.ID,
+		CreatedAt:   post.CreatedAt,
+		UpdatedAt:   post.UpdatedAt,
+		Title:       post.Title,
+		Url:         post.Url,
+		Description: post.Description,
+		PublishedAt: post.PublishedAt,
+		FeedID:      post.FeedID,
+		Content:     post.Content,
+		FeedName:    feed.Name,
+	}))
+}
+
+func (a *apiServer) getPost(w http.ResponseWriter, r *http.Request, _ database.User) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "post not found")
+		return
+	}
+	post, err := a.s.db.GetPostByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, http.StatusNotFound, "post not found")
+			return
+		}
+		internalError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, apiPostFrom(post))
+}
+
+// deletePost deletes a post from a feed the caller added.
+func (a *apiServer) deletePost(w http.ResponseWriter, r *http.Request, user database.User) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "post not found")
+		return
+	}
+	post, err := a.s.db.GetPostByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, http.StatusNotFound, "post not found")
+			return
+		}
+		internalError(w, err)
+		return
+	}
+	feed, err := a.s.db.GetFeedByID(r.Context(), post.FeedID)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	if feed.UserID != user.ID {
+		writeError(w, http.StatusForbidden, "only the user who added a feed can delete its posts")
+		return
+	}
+	n, err := a.s.db.DeletePost(r.Context(), id)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	if n == 0 {
+		writeError(w, http.StatusNotFound, "post not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// feed loads the feed whose id is in the path, writing a 404 if there is
+// none.
+func (a *apiServer) feed(w http.ResponseWriter, r *http.Request) (database.Feed, bool) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "feed not found")
+		return database.Feed{}, false
+	}
+	feed, err := a.s.db.GetFeedByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, http.StatusNotFound, "feed not found")
+		} else {
+			internalError(w, err)
+		}
+		return database.Feed{}, false
+	}
+	return feed, true
+}
+
+// queryLimit reads the limit query parameter, writing a 400 if it is
+// invalid.
+func queryLimit(w http.ResponseWriter, r *http.Request) (int32, bool) {
+	v := r.URL.Query().Get("limit")
+	if v == "" {
+		return apiDefaultLimit, true
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 || n > apiMaxLimit {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", apiMaxLimit))
+		return 0, false
+	}
+	return int32(n), true
+}
+
+// decodeJSON reads a JSON request body into v, writing a 400 if it can't.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, apiMaxBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// requestScheme is the scheme the client used, also behind a TLS-terminating
+// proxy.
+func requestScheme(r *http.Request) string {
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		return "https"
+	}
+	return "http"
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("api: error writing response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+func unauthorized(w http.ResponseWriter, msg string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="gator"`)
+	writeError(w, http.StatusUnauthorized, msg)
+}
+
+func internalError(w http.ResponseWriter, err error) {
+	log.Printf("api: %v", err)
+	writeError(w, http.StatusInternalServerError, "internal error")
+}
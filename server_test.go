@@ -0,0 +1,614 @@
+package main
+
+import (
+	"cmp"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"gator/internal/database"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// apiTest runs the API against users, tokens, feeds, follows and posts kept
+// in memory by a fakeDB. Requests are made as alice unless a test passes
+// another token.
+type apiTest struct {
+	t     *testing.T
+	s     *state
+	srv   *httptest.Server
+	alice database.User
+	token string
+
+	mu      sync.Mutex
+	users   []database.User
+	tokens  map[string]apiTestToken // by hash
+	feeds   []database.Feed
+	follows []database.GetFeedFollowsForUserRow
+	posts   []database.GetPostsForUserRow
+	queried []string // the GetPostsForUser variants run, in order
+}
+
+type apiTestToken struct {
+	id     uuid.UUID
+	userID uuid.UUID
+}
+
+func newAPITest(t *testing.T) *apiTest {
+	a := &apiTest{t: t, tokens: map[string]apiTestToken{}}
+	a.alice = a.addUser("alice")
+	a.token = a.addToken(a.alice)
+
+	db := newFakeDB()
+	db.on("GetUserByAPIToken", a.getUserByAPIToken)
+	db.on("DeleteAPIToken", a.deleteAPIToken)
+	db.on("GetUsers", a.getUsers)
+	db.on("CreateUser", a.createUser)
+	db.on("DeleteUser", a.deleteUser)
+	db.on("GetFeeds", a.getFeeds)
+	db.on("GetFeedByID", a.getFeedBy(func(f database.Feed) string { return f.ID.String() }))
+	db.on("GetFeedByURL", a.getFeedBy(func(f database.Feed) string { return f.Url }))
+	db.on("CreateFeed", a.createFeed)
+	db.on("DeleteFeed", a.deleteFeed)
+	db.on("GetFeedFollowsForUser", a.getFeedFollowsForUser)
+	db.on("CreateFeedFollow", a.createFeedFollow)
+	db.on("DeleteFeedFollow", a.deleteFeedFollow)
+	db.on("GetPostsForUser", a.getPostsForUser("GetPostsForUser", 1))
+	db.on("GetPostsForUserWithMuted", a.getPostsForUser("GetPostsForUserWithMuted", 1))
+	db.on("GetFilteredPostsForUser", a.getPostsForUser("GetFilteredPostsForUser", 7))
+	db.on("GetFilteredPostsForUserWithMuted", a.getPostsForUser("GetFilteredPostsForUserWithMuted", 7))
+
+	a.s = db.state(t)
+	a.srv = httptest.NewServer(newAPIServer(a.s).handler())
+	t.Cleanup(a.srv.Close)
+	return a
+}
+
+func (a *apiTest) addUser(name string) database.User {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := time.Now().UTC()
+	u := database.User{ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Name: name}
+	a.users = append(a.users, u)
+	return u
+}
+
+func (a *apiTest) addToken(user database.User) string {
+	a.t.Helper()
+	token, err := newAPIToken()
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.tokens[hashAPIToken(token)] = apiTestToken{id: uuid.New(), userID: user.ID}
+	return token
+}
+
+func (a *apiTest) addFeed(name, url string, owner database.User) database.Feed {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := time.Now().UTC()
+	f := database.Feed{ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Name: name, Url: url, UserID: owner.ID}
+	a.feeds = append(a.feeds, f)
+	return f
+}
+
+// request makes a request with token, encoding body as JSON unless it is
+// nil, and decodes the JSON response into out unless that is nil.
+func (a *apiTest) request(method, path, token string, body, out any) *http.Response {
+	a.t.Helper()
+	var payload strings.Builder
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			a.t.Fatal(err)
+		}
+	}
+	req, err := http.NewRequest(method, a.srv.URL+path, strings.NewReader(payload.String()))
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := a.srv.Client().Do(req)
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if out != nil && resp.StatusCode < 300 {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			a.t.Fatalf("%s %s: decode response: %v", method, path, err)
+		}
+	}
+	return resp
+}
+
+// expect makes a request as alice and checks the response status.
+func (a *apiTest) expect(method, path string, body any, status int, out any) {
+	a.t.Helper()
+	if resp := a.request(method, path, a.token, body, out); resp.StatusCode != status {
+		a.t.Fatalf("%s %s: got status %d, want %d", method, path, resp.StatusCode, status)
+	}
+}
+
+func userRow(u database.User) []driver.Value {
+	return fakeRow(u.ID, u.CreatedAt, u.UpdatedAt, u.Name, u.Email)
+}
+
+func feedRow(f database.Feed) []driver.Value {
+	return fakeRow(f.ID, f.CreatedAt, f.UpdatedAt, f.Name, f.Url, f.UserID,
+		f.LastFetchedAt, f.NextFetchAt, f.PollInterval, f.RefreshInterval)
+}
+
+func (a *apiTest) getUserByAPIToken(args []driver.Value) ([][]driver.Value, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	tok, ok := a.tokens[args[1].(string)]
+	if !ok {
+		return nil, nil
+	}
+	for _, u := range a.users {
+		if u.ID == tok.userID {
+			return [][]driver.Value{userRow(u)}, nil
+		}
+	}
+	return nil, nil
+}
+
+func (a *apiTest) deleteAPIToken(args []driver.Value) ([][]driver.Value, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for hash, tok := range a.tokens {
+		if tok.id.String() == args[0] && tok.userID.String() == args[1] {
+			delete(a.tokens, hash)
+			return [][]driver.Value{nil}, nil
+		}
+	}
+	return nil, nil
+}
+
+func (a *apiTest) getUsers([]driver.Value) ([][]driver.Value, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var rows [][]driver.Value
+	for _, u := range a.users {
+		rows = append(rows, userRow(u))
+	}
+	return rows, nil
+}
+
+func (a *apiTest) createUser(args []driver.Value) ([][]driver.Value, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	name := args[3].(string)
+	if slices.ContainsFunc(a.users, func(u database.User) bool { return u.Name == name }) {
+		return nil, &pq.Error{Code: "23505"}
+	}
+	u := database.User{
+		ID:        uuid.MustParse(args[0].(string)),
+		CreatedAt: args[1].(time.Time),
+		UpdatedAt: args[2].(time.Time),
+		Name:      name,
+	}
+	a.users = append(a.users, u)
+	return [][]driver.Value{userRow(u)}, nil
+}
+
+func (a *apiTest) deleteUser(args []driver.Value) ([][]driver.Value, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	n := len(a.users)
+	a.users = slices.DeleteFunc(a.users, func(u database.User) bool { return u.Name == args[0] })
+	return make([][]driver.Value, n-len(a.users)), nil
+}
+
+func (a *apiTest) getFeeds([]driver.Value) ([][]driver.Value, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var rows [][]driver.Value
+	for _, f := range a.feeds {
+		rows = append(rows, fakeRow(f.ID, f.CreatedAt, f.UpdatedAt, f.Name, f.Url, f.UserID, "owner"))
+	}
+	return rows, nil
+}
+
+// getFeedBy answers a query for the feed whose key is the first argument.
+func (a *apiTest) getFeedBy(key func(database.Feed) string) fakeQuery {
+	return func(args []driver.Value) ([][]driver.Value, error) {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		for _, f := range a.feeds {
+			if key(f) == args[0] {
+				return [][]driver.Value{feedRow(f)}, nil
+			}
+		}
+		return nil, nil
+	}
+}
+
+func (a *apiTest) createFeed(args []driver.Value) ([][]driver.Value, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	url := args[4].(string)
+	if slices.ContainsFunc(a.feeds, func(f database.Feed) bool { return f.Url == url }) {
+		return nil, &pq.Error{Code: "23505"}
+	}
+	f := database.Feed{
+		ID:        uuid.MustParse(args[0].(string)),
+		CreatedAt: args[1].(time.Time),
+		UpdatedAt: args[2].(time.Time),
+		Name:      args[3].(string),
+		Url:       url,
+		UserID:    uuid.MustParse(args[5].(string)),
+	}
+	a.feeds = append(a.feeds, f)
+	return [][]driver.Value{feedRow(f)}, nil
+}
+
+func (a *apiTest) deleteFeed(args []driver.Value) ([][]driver.Value, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	n := len(a.feeds)
+	a.feeds = slices.DeleteFunc(a.feeds, func(f database.Feed) bool { return f.ID.String() == args[0] })
+	a.follows = slices.DeleteFunc(a.follows, func(ff database.GetFeedFollowsForUserRow) bool {
+		return ff.FeedID.String() == args[0]
+	})
+	return make([][]driver.Value, n-len(a.feeds)), nil
+}
+
+func (a *apiTest) getFeedFollowsForUser(args []driver.Value) ([][]driver.Value, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var rows [][]driver.Value
+	for _, ff := range a.follows {
+		if ff.UserID.String() == args[0] {
+			rows = append(rows, fakeRow(ff.ID, ff.CreatedAt, ff.UpdatedAt, ff.UserID, ff.FeedID,
+				pq.Array(ff.Tags), ff.UserName, ff.FeedName, ff.FeedUrl))
+		}
+	}
+	return rows, nil
+}
+
+func (a *apiTest) createFeedFollow(args []driver.Value) ([][]driver.Value, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	ff := database.GetFeedFollowsForUserRow{
+		ID:        uuid.MustParse(args[0].(string)),
+		CreatedAt: args[1].(time.Time),
+		UpdatedAt: args[2].(time.Time),
+		UserID:    uuid.MustParse(args[3].(string)),
+		FeedID:    uuid.MustParse(args[4].(string)),
+		Tags:      []string{},
+	}
+	for _, other := range a.follows {
+		if other.UserID == ff.UserID && other.FeedID == ff.FeedID {
+			return nil, &pq.Error{Code: "23505"}
+		}
+	}
+	for _, u := range a.users {
+		if u.ID == ff.UserID {
+			ff.UserName = u.Name
+		}
+	}
+	for _, f := range a.feeds {
+		if f.ID == ff.FeedID {
+			ff.FeedName, ff.FeedUrl = f.Name, f.Url
+		}
+	}
+	a.follows = append(a.follows, ff)
+	return [][]driver.Value{fakeRow(ff.ID, ff.CreatedAt, ff.UpdatedAt, ff.UserID, ff.FeedID, ff.UserName, ff.FeedName)}, nil
+}
+
+func (a *apiTest) deleteFeedFollow(args []driver.Value) ([][]driver.Value, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	n := len(a.follows)
+	a.follows = slices.DeleteFunc(a.follows, func(ff database.GetFeedFollowsForUserRow) bool {
+		return ff.UserID.String() == args[0] && ff.FeedID.String() == args[1]
+	})
+	return make([][]driver.Value, n-len(a.follows)), nil
+}
+
+// comparePosts orders posts like GetPostsForUser's keyset, oldest first.
+func comparePosts(p database.GetPostsForUserRow, c postCursor) int {
+	pc := cursorForPost(p)
+	return cmp.Or(
+		pc.PublishedAt.Compare(c.PublishedAt),
+		pc.CreatedAt.Compare(c.CreatedAt),
+		strings.Compare(pc.ID.String(), c.ID.String()),
+	)
+}
+
+// getPostsForUser answers the GetPostsForUser variant called name, whose
+// paging arguments start at args[first]. It pages through all posts the way
+// the real queries do, ignoring filters and mutes.
+func (a *apiTest) getPostsForUser(name string, first int) fakeQuery {
+	return func(args []driver.Value) ([][]driver.Value, error) {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		a.queried = append(a.queried, name)
+		return a.pagePosts(args[first:])
+	}
+}
+
+func (a *apiTest) pagePosts(args []driver.Value) ([][]driver.Value, error) {
+	direction := args[0].(string)
+	cursor := postCursor{PublishedAt: args[1].(time.Time), CreatedAt: args[2].(time.Time), ID: uuid.MustParse(args[3].(string))}
+	limit, offset := args[4].(int64), args[5].(int64)
+
+	var posts []database.GetPostsForUserRow
+	for _, p := range a.posts {
+		c := comparePosts(p, cursor)
+		if direction == "" || direction == "before" && c < 0 || direction == "after" && c > 0 {
+			posts = append(posts, p)
+		}
+	}
+	slices.SortFunc(posts, func(p, q database.GetPostsForUserRow) int {
+		c := comparePosts(p, cursorForPost(q))
+		if direction == "after" {
+			return c
+		}
+		return -c
+	})
+
+	var rows [][]driver.Value
+	for _, p := range posts[min(offset, int64(len(posts))):min(offset+limit, int64(len(posts)))] {
+		rows = append(rows, fakeRow(p.ID, p.CreatedAt, p.UpdatedAt, p.Title, p.Url,
+			p.Description, p.PublishedAt, p.FeedID, p.Content, p.FeedName))
+	}
+	return rows, nil
+}
+
+func TestAPIAuth(t *testing.T) {
+	a := newAPITest(t)
+	revoked := a.addToken(a.alice)
+	var revokedID uuid.UUID
+	for hash, tok := range a.tokens {
+		if hash == hashAPIToken(revoked) {
+			revokedID = tok.id
+		}
+	}
+	if err := handlerRevokeToken(a.s, command{name: "revoketoken", args: []string{revokedID.String()}}, a.alice); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{"missing", "", http.StatusUnauthorized},
+		{"not bearer", "Basic " + a.token, http.StatusUnauthorized},
+		{"empty bearer", "Bearer ", http.StatusUnauthorized},
+		{"bad", "Bearer gator_nope", http.StatusUnauthorized},
+		{"revoked", "Bearer " + revoked, http.StatusUnauthorized},
+		{"valid", "Bearer " + a.token, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", a.srv.URL+"/api/me", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			resp, err := a.srv.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.want {
+				t.Fatalf("got status %d, want %d", resp.StatusCode, tt.want)
+			}
+			if tt.want == http.StatusUnauthorized {
+				if got := resp.Header.Get("WWW-Authenticate"); !strings.HasPrefix(got, "Bearer") {
+					t.Errorf("WWW-Authenticate = %q, want a Bearer challenge", got)
+				}
+				return
+			}
+			var me apiUser
+			if err := json.NewDecoder(resp.Body).Decode(&me); err != nil {
+				t.Fatal(err)
+			}
+			if me.ID != a.alice.ID || me.Name != "alice" {
+				t.Errorf("got user %+v, want alice", me)
+			}
+		})
+	}
+}
+
+func TestAPIUsers(t *testing.T) {
+	a := newAPITest(t)
+
+	var created apiUser
+	a.expect("POST", "/api/users", map[string]string{"name": "bob"}, http.StatusCreated, &created)
+	if created.Name != "bob" || created.ID == uuid.Nil {
+		t.Fatalf("created %+v, want bob", created)
+	}
+	a.expect("POST", "/api/users", map[string]string{"name": "bob"}, http.StatusConflict, nil)
+	a.expect("POST", "/api/users", map[string]string{"name": ""}, http.StatusBadRequest, nil)
+	a.expect("POST", "/api/users", map[string]string{"nickname": "carol"}, http.StatusBadRequest, nil)
+
+	var users []apiUser
+	a.expect("GET", "/api/users", nil, http.StatusOK, &users)
+	var names []string
+	for _, u := range users {
+		names = append(names, u.Name)
+	}
+	if !slices.Equal(names, []string{"alice", "bob"}) {
+		t.Fatalf("listed users %v, want [alice bob]", names)
+	}
+
+	a.expect("DELETE", "/api/users/bob", nil, http.StatusForbidden, nil)
+	a.expect("DELETE", "/api/users/alice", nil, http.StatusNoContent, nil)
+	if slices.ContainsFunc(a.users, func(u database.User) bool { return u.Name == "alice" }) {
+		t.Error("alice was not deleted")
+	}
+}
+
+func TestAPIFeeds(t *testing.T) {
+	a := newAPITest(t)
+	bob := a.addUser("bob")
+	bobs := a.addFeed("Bob's", "https://bob.example/feed", bob)
+
+	var created apiFeed
+	body := map[string]string{"name": "Example", "url": "https://example.com/feed"}
+	a.expect("POST", "/api/feeds", body, http.StatusCreated, &created)
+	if created.Name != "Example" || created.URL != body["url"] || created.UserID != a.alice.ID {
+		t.Fatalf("created %+v, want alice's Example feed", created)
+	}
+	a.expect("POST", "/api/feeds", body, http.StatusConflict, nil)
+	a.expect("POST", "/api/feeds", map[string]string{"name": "No URL"}, http.StatusBadRequest, nil)
+
+	var follows []apiFollow
+	a.expect("GET", "/api/follows", nil, http.StatusOK, &follows)
+	if len(follows) != 1 || follows[0].FeedID != created.ID {
+		t.Fatalf("follows after creating a feed: %+v, want the new feed", follows)
+	}
+
+	var feeds []apiFeed
+	a.expect("GET", "/api/feeds", nil, http.StatusOK, &feeds)
+	if len(feeds) != 2 || feeds[0].ID != bobs.ID || feeds[1].ID != created.ID {
+		t.Fatalf("listed feeds %+v, want Bob's and Example", feeds)
+	}
+
+	var got apiFeed
+	a.expect("GET", "/api/feeds/"+created.ID.String(), nil, http.StatusOK, &got)
+	if got != created {
+		t.Errorf("got feed %+v, want %+v", got, created)
+	}
+	a.expect("GET", "/api/feeds/"+uuid.NewString(), nil, http.StatusNotFound, nil)
+	a.expect("GET", "/api/feeds/not-a-uuid", nil, http.StatusNotFound, nil)
+
+	a.expect("DELETE", "/api/feeds/"+bobs.ID.String(), nil, http.StatusForbidden, nil)
+	a.expect("DELETE", "/api/feeds/"+created.ID.String(), nil, http.StatusNoContent, nil)
+	a.expect("GET", "/api/feeds/"+created.ID.String(), nil, http.StatusNotFound, nil)
+}
+
+func TestAPIFollows(t *testing.T) {
+	a := newAPITest(t)
+	bob := a.addUser("bob")
+	feed := a.addFeed("Bob's", "https://bob.example/feed", bob)
+
+	var created apiFollow
+	a.expect("POST", "/api/follows", map[string]string{"feed_url": feed.Url}, http.StatusCreated, &created)
+	if created.FeedID != feed.ID || created.FeedName != "Bob's" {
+		t.Fatalf("created %+v, want a follow of Bob's", created)
+	}
+	a.expect("POST", "/api/follows", map[string]string{"feed_url": feed.Url}, http.StatusConflict, nil)
+	a.expect("POST", "/api/follows", map[string]string{"feed_url": "https://nowhere.example/feed"}, http.StatusBadRequest, nil)
+
+	a.mu.Lock()
+	a.follows[0].Tags = []string{"friends", "tech"}
+	a.mu.Unlock()
+
+	var follows []apiFollow
+	a.expect("GET", "/api/follows", nil, http.StatusOK, &follows)
+	if len(follows) != 1 || follows[0].FeedID != feed.ID || !slices.Equal(follows[0].Tags, []string{"friends", "tech"}) {
+		t.Fatalf("listed follows %+v, want Bob's tagged friends and tech", follows)
+	}
+
+	a.expect("DELETE", "/api/follows/"+feed.ID.String(), nil, http.StatusNoContent, nil)
+	a.expect("GET", "/api/follows", nil, http.StatusOK, &follows)
+	if len(follows) != 0 {
+		t.Fatalf("follows after unfollowing: %+v, want none", follows)
+	}
+	a.expect("DELETE", "/api/follows/not-a-uuid", nil, http.StatusNotFound, nil)
+}
+
+func TestAPIBrowsePagination(t *testing.T) {
+	a := newAPITest(t)
+	feed := a.addFeed("Example", "https://example.com/feed", a.alice)
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	var titles []string // newest first
+	for i := range 5 {
+		title := fmt.Sprintf("post %d", i)
+		a.posts = append(a.posts, database.GetPostsForUserRow{
+			ID:          uuid.New(),
+			CreatedAt:   base,
+			UpdatedAt:   base,
+			Title:       title,
+			Url:         "https://example.com/" + title,
+			PublishedAt: sql.NullTime{Time: base.Add(time.Duration(i) * time.Hour), Valid: true},
+			FeedID:      feed.ID,
+			FeedName:    feed.Name,
+		})
+		titles = slices.Insert(titles, 0, title)
+	}
+
+	type page struct {
+		Posts []apiPost `json:"posts"`
+		Newer string    `json:"newer"`
+		Older string    `json:"older"`
+	}
+	browse := func(query string, wantTitles []string) page {
+		t.Helper()
+		var p page
+		a.expect("GET", "/api/posts?limit=2"+query, nil, http.StatusOK, &p)
+		var got []string
+		for _, post := range p.Posts {
+			got = append(got, post.Title)
+		}
+		if !slices.Equal(got, wantTitles) {
+			t.Fatalf("browse %q: got %v, want %v", query, got, wantTitles)
+		}
+		return p
+	}
+
+	first := browse("", titles[0:2])
+	if first.Newer != "" || first.Older == "" {
+		t.Fatalf("first page cursors: newer %q, older %q; want only older", first.Newer, first.Older)
+	}
+	second := browse("&before="+first.Older, titles[2:4])
+	if second.Newer == "" || second.Older == "" {
+		t.Fatalf("second page cursors: newer %q, older %q; want both", second.Newer, second.Older)
+	}
+	last := browse("&before="+second.Older, titles[4:5])
+	if last.Older != "" {
+		t.Fatalf("last page has an older cursor %q", last.Older)
+	}
+	back := browse("&after="+last.Newer, titles[2:4])
+	if back.Older != second.Older {
+		t.Errorf("going back: older cursor %q, want %q", back.Older, second.Older)
+	}
+	browse("&after="+second.Newer, titles[0:2])
+
+	a.expect("GET", "/api/posts?before=x&after=y", nil, http.StatusBadRequest, nil)
+	a.expect("GET", "/api/posts?before=not-a-cursor", nil, http.StatusBadRequest, nil)
+	a.expect("GET", "/api/posts?limit=0", nil, http.StatusBadRequest, nil)
+}
+
+func TestAPIBrowseQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"", "GetPostsForUser"},
+		{"?page=2", "GetPostsForUser"},
+		{"?show_muted=true", "GetPostsForUserWithMuted"},
+		{"?unread=true", "GetFilteredPostsForUser"},
+		{"?match=go", "GetFilteredPostsForUser"},
+		{"?tag=go", "GetFilteredPostsForUser"},
+		{"?since=2024-05-01", "GetFilteredPostsForUser"},
+		{"?until=2024-05-01&show_muted=true", "GetFilteredPostsForUserWithMuted"},
+	}
+	for _, tt := range tests {
+		a := newAPITest(t)
+		a.expect("GET", "/api/posts"+tt.query, nil, http.StatusOK, nil)
+		if !slices.Equal(a.queried, []string{tt.want}) {
+			t.Errorf("browse %q ran %v, want %s", tt.query, a.queried, tt.want)
+		}
+	}
+}
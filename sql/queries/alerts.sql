@@ -0,0 +1,75 @@
+-- name: CreateAlertRule :one
+INSERT INTO alert_rules (id, created_at, user_id, feed_id, kind, pattern, notify)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- name: GetAlertRulesForUser :many
+SELECT
+  alert_rules.id,
+  alert_rules.created_at,
+  alert_rules.feed_id,
+  alert_rules.kind,
+  alert_rules.pattern,
+  alert_rules.notify,
+  feeds.name AS feed_name,
+  COUNT(alerts.id) AS matches
+FROM alert_rules
+LEFT JOIN feeds ON feeds.id = alert_rules.feed_id
+LEFT JOIN alerts ON alerts.rule_id = alert_rules.id
+WHERE alert_rules.user_id = $1
+GROUP BY alert_rules.id, feeds.name
+ORDER BY alert_rules.created_at;
+
+-- name: DeleteAlertRule :execrows
+DELETE FROM alert_rules
+WHERE id = $1 AND user_id = $2;
+
+-- name: GetAlertRulesForFeed :many
+-- The rules new posts in a feed are checked against: those limited to the
+-- feed, and the unlimited ones of users who follow it.
+SELECT
+  alert_rules.id,
+  alert_rules.kind,
+  alert_rules.pattern,
+  alert_rules.notify,
+  users.name AS user_name
+FROM alert_rules
+JOIN users ON users.id = alert_rules.user_id
+WHERE alert_rules.feed_id = sqlc.arg(feed_id)::uuid
+  OR (
+    alert_rules.feed_id IS NULL
+    AND EXISTS (
+      SELECT 1 FROM feed_follows
+      WHERE feed_follows.user_id = alert_rules.user_id
+        AND feed_follows.feed_id = sqlc.arg(feed_id)::uuid
+    )
+  )
+ORDER BY alert_rules.created_at;
+
+-- name: CreateAlert :execrows
+INSERT INTO alerts (id, created_at, rule_id, post_id)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (rule_id, post_id) DO NOTHING;
+
+-- name: GetAlertsForUser :many
+-- The user's most recent matches, optionally only those of one rule.
+SELECT
+  alerts.id,
+  alerts.created_at,
+  alerts.rule_id,
+  alert_rules.kind,
+  alert_rules.pattern,
+  posts.id AS post_id,
+  posts.title,
+  posts.url,
+  posts.author,
+  posts.published_at,
+  feeds.name AS feed_name
+FROM alerts
+JOIN alert_rules ON alert_rules.id = alerts.rule_id
+JOIN posts ON posts.id = alerts.post_id
+JOIN feeds ON feeds.id = posts.feed_id
+WHERE alert_rules.user_id = sqlc.arg(user_id)
+  AND (sqlc.narg(rule_id)::uuid IS NULL OR alerts.rule_id = sqlc.narg(rule_id))
+ORDER BY alerts.created_at DESC
+LIMIT sqlc.arg('limit');
@@ -0,0 +1,24 @@
+-- name: CreateAPIToken :one
+INSERT INTO api_tokens (id, created_at, user_id, name, token_hash, prefix)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetAPITokensForUser :many
+SELECT * FROM api_tokens
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: DeleteAPIToken :execrows
+DELETE FROM api_tokens
+WHERE id = $1 AND user_id = $2;
+
+-- name: GetUserByAPIToken :one
+-- Also records when the token was last used.
+WITH used AS (
+  UPDATE api_tokens
+  SET last_used_at = sqlc.arg(used_at)::timestamp
+  WHERE token_hash = sqlc.arg(token_hash)
+  RETURNING user_id
+)
+SELECT users.* FROM users
+JOIN used ON used.user_id = users.id;
@@ -0,0 +1,29 @@
+-- name: GetDigestPosts :many
+-- Posts from the user's followed feeds that arrived since the cutoff and
+-- haven't been in one of their digests yet, grouped by feed.
+SELECT
+  posts.id,
+  posts.created_at,
+  posts.title,
+  posts.url,
+  posts.description,
+  posts.published_at,
+  feeds.name AS feed_name,
+  feeds.url AS feed_url
+FROM posts
+JOIN feeds ON feeds.id = posts.feed_id
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+  AND posts.created_at >= sqlc.arg(since)::timestamp
+  AND NOT EXISTS (
+    SELECT 1 FROM digest_posts
+    WHERE digest_posts.user_id = sqlc.arg(user_id)
+      AND digest_posts.post_id = posts.id
+  )
+ORDER BY feeds.name, feeds.url, COALESCE(posts.published_at, posts.created_at) DESC;
+
+-- name: RecordDigestPosts :execrows
+INSERT INTO digest_posts (user_id, post_id, sent_at)
+SELECT sqlc.arg(user_id)::uuid, post_id, sqlc.arg(sent_at)::timestamp
+FROM unnest(sqlc.arg(post_ids)::uuid[]) AS post_id
+ON CONFLICT (user_id, post_id) DO NOTHING;
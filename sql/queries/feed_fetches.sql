@@ -0,0 +1,23 @@
+-- name: CreateFeedFetch :exec
+INSERT INTO feed_fetches (
+  id, feed_id, started_at, finished_at,
+  http_status, bytes, items_seen, posts_inserted, error
+) VALUES (
+  $1, $2, $3, $4,
+  $5, $6, $7, $8, $9
+);
+
+-- name: GetFeedFetches :many
+SELECT
+  feed_fetches.*,
+  feeds.name AS feed_name,
+  feeds.url AS feed_url
+FROM feed_fetches
+JOIN feeds ON feeds.id = feed_fetches.feed_id
+WHERE sqlc.narg(feed_url)::text IS NULL OR feeds.url = sqlc.narg(feed_url)::text
+ORDER BY feed_fetches.started_at DESC
+LIMIT sqlc.arg('limit');
+
+-- name: DeleteFeedFetchesBefore :execrows
+DELETE FROM feed_fetches
+WHERE started_at < $1;
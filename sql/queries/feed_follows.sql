@@ -24,8 +24,10 @@ SELECT
   ff.updated_at,
   ff.user_id,
   ff.feed_id,
+  ff.tags,
   users.name AS user_name,
-  feeds.name AS feed_name
+  feeds.name AS feed_name,
+  feeds.url AS feed_url
 FROM feed_follows ff
 JOIN users ON users.id = ff.user_id
 JOIN feeds ON feeds.id = ff.feed_id
@@ -36,3 +38,21 @@ ORDER BY ff.created_at ASC;
 -- name: DeleteFeedFollow :exec
 DELETE FROM feed_follows
 WHERE user_id = $1 AND feed_id = $2;
+
+
+-- name: TagFeedFollow :execrows
+UPDATE feed_follows
+SET tags = ARRAY(
+    SELECT DISTINCT t FROM unnest(array_append(tags, sqlc.arg(tag)::text)) AS t ORDER BY t
+  ),
+  updated_at = sqlc.arg(updated_at)
+WHERE user_id = sqlc.arg(user_id) AND feed_id = sqlc.arg(feed_id);
+
+
+-- name: UntagFeedFollow :execrows
+UPDATE feed_follows
+SET tags = array_remove(tags, sqlc.arg(tag)::text),
+  updated_at = sqlc.arg(updated_at)
+WHERE user_id = sqlc.arg(user_id)
+  AND feed_id = sqlc.arg(feed_id)
+  AND sqlc.arg(tag)::text = ANY(tags);
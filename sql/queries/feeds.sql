@@ -16,6 +16,10 @@ FROM feeds
 JOIN users ON feeds.user_id = users.id
 ORDER BY feeds.created_at ASC;
 
+-- name: GetFeedByID :one
+SELECT * FROM feeds
+WHERE id = $1;
+
 -- name: GetFeedByURL :one
 SELECT * FROM feeds
 WHERE url = $1;
@@ -23,11 +27,59 @@ WHERE url = $1;
 -- name: MarkFeedFetched :exec
 UPDATE feeds
 SET last_fetched_at = NOW(),
+    next_fetch_at = $2,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: SetFeedNextFetch :exec
+UPDATE feeds
+SET next_fetch_at = $2,
     updated_at = NOW()
 WHERE id = $1;
 
--- name: GetNextFeedToFetch :one
+-- name: SetFeedSchedule :exec
+UPDATE feeds
+SET poll_interval = $2,
+    next_fetch_at = $3,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: SetFeedRefreshInterval :exec
+UPDATE feeds
+SET refresh_interval = $2,
+    next_fetch_at = $3,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: GetFeedStats :many
+SELECT
+  feeds.id,
+  feeds.name,
+  feeds.url,
+  feeds.last_fetched_at,
+  feeds.next_fetch_at,
+  feeds.poll_interval,
+  feeds.refresh_interval,
+  COUNT(posts.id) AS post_count
+FROM feeds
+LEFT JOIN posts ON posts.feed_id = feeds.id
+WHERE sqlc.narg(url)::text IS NULL OR feeds.url = sqlc.narg(url)::text
+GROUP BY feeds.id
+ORDER BY feeds.name ASC;
+
+-- name: GetNextFeedsToFetch :many
 SELECT *
 FROM feeds
-ORDER BY last_fetched_at NULLS FIRST, created_at ASC
-LIMIT 1;
\ No newline at end of file
+WHERE next_fetch_at IS NULL OR next_fetch_at <= sqlc.arg(now)::timestamp
+ORDER BY next_fetch_at NULLS FIRST, created_at ASC
+LIMIT sqlc.arg('limit');
+
+-- name: GetEarliestNextFetch :one
+SELECT next_fetch_at
+FROM feeds
+ORDER BY next_fetch_at NULLS FIRST
+LIMIT 1;
+
+-- name: DeleteFeed :execrows
+DELETE FROM feeds
+WHERE id = $1;
@@ -0,0 +1,38 @@
+-- name: CreateMuteRule :one
+INSERT INTO mute_rules (id, created_at, user_id, feed_id, kind, pattern)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetMuteRulesForUser :many
+-- Each rule with how many posts it currently hides from the user.
+SELECT
+  mute_rules.id,
+  mute_rules.created_at,
+  mute_rules.feed_id,
+  mute_rules.kind,
+  mute_rules.pattern,
+  feeds.name AS feed_name,
+  (
+    SELECT COUNT(*) FROM posts
+    JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+    WHERE feed_follows.user_id = mute_rules.user_id
+      AND (mute_rules.feed_id IS NULL OR mute_rules.feed_id = posts.feed_id)
+      AND (
+        (mute_rules.kind = 'title' AND posts.title ~* mute_rules.pattern)
+        OR (mute_rules.kind = 'category' AND lower(mute_rules.pattern) IN (SELECT lower(c) FROM unnest(posts.categories) AS c))
+        OR (mute_rules.kind = 'author' AND strpos(lower(posts.author), lower(mute_rules.pattern)) > 0)
+      )
+  ) AS muted
+FROM mute_rules
+LEFT JOIN feeds ON feeds.id = mute_rules.feed_id
+WHERE mute_rules.user_id = $1
+ORDER BY mute_rules.created_at;
+
+-- name: DeleteMuteRule :execrows
+DELETE FROM mute_rules
+WHERE id = $1 AND user_id = $2;
+
+-- name: CheckRegex :one
+-- Errors if pattern isn't a regex Postgres accepts, so a bad title rule
+-- can't break browsing.
+SELECT '' ~* sqlc.arg(pattern)::text;
@@ -0,0 +1,65 @@
+-- name: MarkPostsRead :execrows
+INSERT INTO post_reads (user_id, post_id, read_at)
+SELECT sqlc.arg(user_id)::uuid, posts.id, sqlc.arg(read_at)::timestamp
+FROM posts
+WHERE posts.id = ANY(sqlc.arg(post_ids)::uuid[])
+ON CONFLICT (user_id, post_id) DO NOTHING;
+
+-- name: MarkFeedPostsRead :execrows
+INSERT INTO post_reads (user_id, post_id, read_at)
+SELECT sqlc.arg(user_id)::uuid, posts.id, sqlc.arg(read_at)::timestamp
+FROM posts
+WHERE posts.feed_id = sqlc.arg(feed_id)::uuid
+ON CONFLICT (user_id, post_id) DO NOTHING;
+
+-- name: MarkPostsReadBefore :execrows
+-- Marks every post in the user's followed feeds older than the cutoff.
+INSERT INTO post_reads (user_id, post_id, read_at)
+SELECT sqlc.arg(user_id)::uuid, posts.id, sqlc.arg(read_at)::timestamp
+FROM posts
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)::uuid
+  AND COALESCE(posts.published_at, posts.created_at) < sqlc.arg(cutoff)::timestamp
+ON CONFLICT (user_id, post_id) DO NOTHING;
+
+-- name: MarkPostsUnread :execrows
+DELETE FROM post_reads
+WHERE user_id = sqlc.arg(user_id)
+  AND post_id = ANY(sqlc.arg(post_ids)::uuid[]);
+
+-- name: MarkFeedPostsUnread :execrows
+DELETE FROM post_reads
+USING posts
+WHERE post_reads.post_id = posts.id
+  AND post_reads.user_id = $1
+  AND posts.feed_id = $2;
+
+-- name: MarkPostsUnreadBefore :execrows
+DELETE FROM post_reads
+USING posts
+WHERE post_reads.post_id = posts.id
+  AND post_reads.user_id = sqlc.arg(user_id)
+  AND COALESCE(posts.published_at, posts.created_at) < sqlc.arg(cutoff)::timestamp;
+
+-- name: GetReadPostIDs :many
+-- Which of the given posts the user has read.
+SELECT post_id FROM post_reads
+WHERE user_id = sqlc.arg(user_id)
+  AND post_id = ANY(sqlc.arg(post_ids)::uuid[]);
+
+-- name: GetUnreadCountsForUser :many
+-- The user's followed feeds with how many of their posts are unread.
+SELECT
+  feeds.id,
+  feeds.name,
+  feeds.url,
+  feeds.user_id,
+  COUNT(posts.id) FILTER (WHERE post_reads.post_id IS NULL) AS unread
+FROM feed_follows
+JOIN feeds ON feeds.id = feed_follows.feed_id
+LEFT JOIN posts ON posts.feed_id = feeds.id
+LEFT JOIN post_reads ON post_reads.post_id = posts.id
+  AND post_reads.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+GROUP BY feeds.id
+ORDER BY feeds.name;
@@ -2,19 +2,364 @@
 INSERT INTO posts (
   id, created_at, updated_at,
   title, url, description, published_at,
-  feed_id
+  feed_id, content, raw_description, raw_content,
+  author, categories
 ) VALUES (
   $1, $2, $3,
   $4, $5, $6, $7,
-  $8
+  $8, $9, $10, $11,
+  $12, $13
 )
 RETURNING *;
 
 -- name: GetPostsForUser :many
-SELECT posts.*
+-- Keyset pagination on (published_at, created_at, id), newest first. Posts
+-- without published_at sort as if published at 0001-01-01, i.e. last.
+-- cursor_direction is empty, 'before' (older than the cursor) or 'after'
+-- (newer than the cursor; returned oldest first, callers reverse them).
+-- Posts the user muted are left out.
+SELECT
+  posts.id, posts.created_at, posts.updated_at,
+  posts.title, posts.url, posts.description, posts.published_at,
+  posts.feed_id, posts.content,
+  feeds.name AS feed_name
 FROM posts
 JOIN feeds ON posts.feed_id = feeds.id
 JOIN feed_follows ON feed_follows.feed_id = feeds.id
-WHERE feed_follows.user_id = $1
-ORDER BY posts.published_at DESC NULLS LAST, posts.created_at DESC
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+  AND (
+    sqlc.arg(cursor_direction)::text = ''
+    OR (
+      sqlc.arg(cursor_direction)::text = 'before'
+      AND (COALESCE(posts.published_at, '0001-01-01'::timestamp), posts.created_at, posts.id)
+        < (sqlc.arg(cursor_published_at)::timestamp, sqlc.arg(cursor_created_at)::timestamp, sqlc.arg(cursor_id)::uuid)
+    )
+    OR (
+      sqlc.arg(cursor_direction)::text = 'after'
+      AND (COALESCE(posts.published_at, '0001-01-01'::timestamp), posts.created_at, posts.id)
+        > (sqlc.arg(cursor_published_at)::timestamp, sqlc.arg(cursor_created_at)::timestamp, sqlc.arg(cursor_id)::uuid)
+    )
+  )
+  AND NOT EXISTS (
+    SELECT 1 FROM mute_rules
+    WHERE mute_rules.user_id = sqlc.arg(user_id)
+      AND (mute_rules.feed_id IS NULL OR mute_rules.feed_id = posts.feed_id)
+      AND (
+        (mute_rules.kind = 'title' AND posts.title ~* mute_rules.pattern)
+        OR (mute_rules.kind = 'category' AND lower(mute_rules.pattern) IN (SELECT lower(c) FROM unnest(posts.categories) AS c))
+        OR (mute_rules.kind = 'author' AND strpos(lower(posts.author), lower(mute_rules.pattern)) > 0)
+      )
+  )
+ORDER BY
+  CASE WHEN sqlc.arg(cursor_direction)::text = 'after' THEN COALESCE(posts.published_at, '0001-01-01'::timestamp) END ASC,
+  CASE WHEN sqlc.arg(cursor_direction)::text = 'after' THEN posts.created_at END ASC,
+  CASE WHEN sqlc.arg(cursor_direction)::text = 'after' THEN posts.id END ASC,
+  COALESCE(posts.published_at, '0001-01-01'::timestamp) DESC,
+  posts.created_at DESC,
+  posts.id DESC
+LIMIT sqlc.arg('limit')
+OFFSET sqlc.arg('offset');
+
+-- name: GetPostsForUserWithMuted :many
+-- Like GetPostsForUser, but keeps the posts the user muted.
+SELECT
+  posts.id, posts.created_at, posts.updated_at,
+  posts.title, posts.url, posts.description, posts.published_at,
+  posts.feed_id, posts.content,
+  feeds.name AS feed_name
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+JOIN feed_follows ON feed_follows.feed_id = feeds.id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+  AND (
+    sqlc.arg(cursor_direction)::text = ''
+    OR (
+      sqlc.arg(cursor_direction)::text = 'before'
+      AND (COALESCE(posts.published_at, '0001-01-01'::timestamp), posts.created_at, posts.id)
+        < (sqlc.arg(cursor_published_at)::timestamp, sqlc.arg(cursor_created_at)::timestamp, sqlc.arg(cursor_id)::uuid)
+    )
+    OR (
+      sqlc.arg(cursor_direction)::text = 'after'
+      AND (COALESCE(posts.published_at, '0001-01-01'::timestamp), posts.created_at, posts.id)
+        > (sqlc.arg(cursor_published_at)::timestamp, sqlc.arg(cursor_created_at)::timestamp, sqlc.arg(cursor_id)::uuid)
+    )
+  )
+ORDER BY
+  CASE WHEN sqlc.arg(cursor_direction)::text = 'after' THEN COALESCE(posts.published_at, '0001-01-01'::timestamp) END ASC,
+  CASE WHEN sqlc.arg(cursor_direction)::text = 'after' THEN posts.created_at END ASC,
+  CASE WHEN sqlc.arg(cursor_direction)::text = 'after' THEN posts.id END ASC,
+  COALESCE(posts.published_at, '0001-01-01'::timestamp) DESC,
+  posts.created_at DESC,
+  posts.id DESC
+LIMIT sqlc.arg('limit')
+OFFSET sqlc.arg('offset');
+
+-- name: GetFilteredPostsForUser :many
+-- Like GetPostsForUser, but only returns posts that pass every filter.
+-- Empty/NULL filters match everything.
+SELECT
+  posts.id, posts.created_at, posts.updated_at,
+  posts.title, posts.url, posts.description, posts.published_at,
+  posts.feed_id, posts.content,
+  feeds.name AS feed_name
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+JOIN feed_follows ON feed_follows.feed_id = feeds.id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+  AND (
+    NOT sqlc.arg(unread_only)::bool
+    OR NOT EXISTS (
+      SELECT 1 FROM post_reads
+      WHERE post_reads.post_id = posts.id
+        AND post_reads.user_id = sqlc.arg(user_id)
+    )
+  )
+  AND (
+    COALESCE(cardinality(sqlc.arg(feeds)::text[]), 0) = 0
+    OR feeds.url = ANY(sqlc.arg(feeds)::text[])
+    OR feeds.name = ANY(sqlc.arg(feeds)::text[])
+  )
+  AND (
+    COALESCE(cardinality(sqlc.arg(tags)::text[]), 0) = 0
+    OR feed_follows.tags && sqlc.arg(tags)::text[]
+  )
+  AND COALESCE(posts.published_at, posts.created_at) >= COALESCE(sqlc.narg(since)::timestamp, '-infinity'::timestamp)
+  AND COALESCE(posts.published_at, posts.created_at) < COALESCE(sqlc.narg(until)::timestamp, 'infinity'::timestamp)
+  AND (
+    posts.title ILIKE '%' || sqlc.arg(keyword)::text || '%'
+    OR posts.description ILIKE '%' || sqlc.arg(keyword)::text || '%'
+  )
+  AND (
+    sqlc.arg(cursor_direction)::text = ''
+    OR (
+      sqlc.arg(cursor_direction)::text = 'before'
+      AND (COALESCE(posts.published_at, '0001-01-01'::timestamp), posts.created_at, posts.id)
+        < (sqlc.arg(cursor_published_at)::timestamp, sqlc.arg(cursor_created_at)::timestamp, sqlc.arg(cursor_id)::uuid)
+    )
+    OR (
+      sqlc.arg(cursor_direction)::text = 'after'
+      AND (COALESCE(posts.published_at, '0001-01-01'::timestamp), posts.created_at, posts.id)
+        > (sqlc.arg(cursor_published_at)::timestamp, sqlc.arg(cursor_created_at)::timestamp, sqlc.arg(cursor_id)::uuid)
+    )
+  )
+  AND NOT EXISTS (
+    SELECT 1 FROM mute_rules
+    WHERE mute_rules.user_id = sqlc.arg(user_id)
+      AND (mute_rules.feed_id IS NULL OR mute_rules.feed_id = posts.feed_id)
+      AND (
+        (mute_rules.kind = 'title' AND posts.title ~* mute_rules.pattern)
+        OR (mute_rules.kind = 'category' AND lower(mute_rules.pattern) IN (SELECT lower(c) FROM unnest(posts.categories) AS c))
+        OR (mute_rules.kind = 'author' AND strpos(lower(posts.author), lower(mute_rules.pattern)) > 0)
+      )
+  )
+ORDER BY
+  CASE WHEN sqlc.arg(cursor_direction)::text = 'after' THEN COALESCE(posts.published_at, '0001-01-01'::timestamp) END ASC,
+  CASE WHEN sqlc.arg(cursor_direction)::text = 'after' THEN posts.created_at END ASC,
+  CASE WHEN sqlc.arg(cursor_direction)::text = 'after' THEN posts.id END ASC,
+  COALESCE(posts.published_at, '0001-01-01'::timestamp) DESC,
+  posts.created_at DESC,
+  posts.id DESC
+LIMIT sqlc.arg('limit')
+OFFSET sqlc.arg('offset');
+
+-- name: GetFilteredPostsForUserWithMuted :many
+-- Like GetFilteredPostsForUser, but keeps the posts the user muted.
+SELECT
+  posts.id, posts.created_at, posts.updated_at,
+  posts.title, posts.url, posts.description, posts.published_at,
+  posts.feed_id, posts.content,
+  feeds.name AS feed_name
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+JOIN feed_follows ON feed_follows.feed_id = feeds.id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+  AND (
+    NOT sqlc.arg(unread_only)::bool
+    OR NOT EXISTS (
+      SELECT 1 FROM post_reads
+      WHERE post_reads.post_id = posts.id
+        AND post_reads.user_id = sqlc.arg(user_id)
+    )
+  )
+  AND (
+    COALESCE(cardinality(sqlc.arg(feeds)::text[]), 0) = 0
+    OR feeds.url = ANY(sqlc.arg(feeds)::text[])
+    OR feeds.name = ANY(sqlc.arg(feeds)::text[])
+  )
+  AND (
+    COALESCE(cardinality(sqlc.arg(tags)::text[]), 0) = 0
+    OR feed_follows.tags && sqlc.arg(tags)::text[]
+  )
+  AND COALESCE(posts.published_at, posts.created_at) >= COALESCE(sqlc.narg(since)::timestamp, '-infinity'::timestamp)
+  AND COALESCE(posts.published_at, posts.created_at) < COALESCE(sqlc.narg(until)::timestamp, 'infinity'::timestamp)
+  AND (
+    posts.title ILIKE '%' || sqlc.arg(keyword)::text || '%'
+    OR posts.description ILIKE '%' || sqlc.arg(keyword)::text || '%'
+  )
+  AND (
+    sqlc.arg(cursor_direction)::text = ''
+    OR (
+      sqlc.arg(cursor_direction)::text = 'before'
+      AND (COALESCE(posts.published_at, '0001-01-01'::timestamp), posts.created_at, posts.id)
+        < (sqlc.arg(cursor_published_at)::timestamp, sqlc.arg(cursor_created_at)::timestamp, sqlc.arg(cursor_id)::uuid)
+    )
+    OR (
+      sqlc.arg(cursor_direction)::text = 'after'
+      AND (COALESCE(posts.published_at, '0001-01-01'::timestamp), posts.created_at, posts.id)
+        > (sqlc.arg(cursor_published_at)::timestamp, sqlc.arg(cursor_created_at)::timestamp, sqlc.arg(cursor_id)::uuid)
+    )
+  )
+ORDER BY
+  CASE WHEN sqlc.arg(cursor_direction)::text = 'after' THEN COALESCE(posts.published_at, '0001-01-01'::timestamp) END ASC,
+  CASE WHEN sqlc.arg(cursor_direction)::text = 'after' THEN posts.created_at END ASC,
+  CASE WHEN sqlc.arg(cursor_direction)::text = 'after' THEN posts.id END ASC,
+  COALESCE(posts.published_at, '0001-01-01'::timestamp) DESC,
+  posts.created_at DESC,
+  posts.id DESC
+LIMIT sqlc.arg('limit')
+OFFSET sqlc.arg('offset');
+
+-- name: GetFeedPublishTimes :many
+SELECT published_at
+FROM posts
+WHERE feed_id = $1 AND published_at IS NOT NULL
+ORDER BY published_at DESC
 LIMIT $2;
+
+-- name: CountPrunablePosts :many
+-- Posts older than the cutoff, or beyond the newest max_posts of their feed.
+-- Saved posts are never pruned.
+WITH ranked AS (
+  SELECT
+    posts.id,
+    posts.feed_id,
+    COALESCE(posts.published_at, posts.created_at) AS posted_at,
+    ROW_NUMBER() OVER (
+      PARTITION BY posts.feed_id
+      ORDER BY posts.published_at DESC NULLS LAST, posts.created_at DESC
+    ) AS position
+  FROM posts
+)
+SELECT feeds.name, feeds.url, COUNT(*) AS prunable
+FROM ranked
+JOIN feeds ON feeds.id = ranked.feed_id
+WHERE (
+    (sqlc.arg(prune_by_age)::bool AND ranked.posted_at < sqlc.arg(cutoff)::timestamp)
+    OR (sqlc.arg(max_posts)::int > 0 AND ranked.position > sqlc.arg(max_posts)::int)
+  )
+  AND NOT EXISTS (SELECT 1 FROM saved_posts WHERE saved_posts.post_id = ranked.id)
+GROUP BY feeds.id, feeds.name, feeds.url
+ORDER BY feeds.name ASC;
+
+-- name: DeletePrunablePosts :execrows
+-- Deletes what CountPrunablePosts counts, remembering the URLs in
+-- pruned_posts.
+WITH ranked AS (
+  SELECT
+    posts.id,
+    posts.feed_id,
+    COALESCE(posts.published_at, posts.created_at) AS posted_at,
+    ROW_NUMBER() OVER (
+      PARTITION BY posts.feed_id
+      ORDER BY posts.published_at DESC NULLS LAST, posts.created_at DESC
+    ) AS position
+  FROM posts
+),
+deleted AS (
+  DELETE FROM posts
+  WHERE posts.id IN (
+    SELECT ranked.id
+    FROM ranked
+    WHERE (
+        (sqlc.arg(prune_by_age)::bool AND ranked.posted_at < sqlc.arg(cutoff)::timestamp)
+        OR (sqlc.arg(max_posts)::int > 0 AND ranked.position > sqlc.arg(max_posts)::int)
+      )
+      AND NOT EXISTS (SELECT 1 FROM saved_posts WHERE saved_posts.post_id = ranked.id)
+  )
+  RETURNING posts.url, posts.feed_id
+)
+INSERT INTO pruned_posts (url, feed_id, pruned_at)
+SELECT url, feed_id, sqlc.arg(pruned_at)::timestamp FROM deleted
+ON CONFLICT (url) DO UPDATE SET feed_id = EXCLUDED.feed_id, pruned_at = EXCLUDED.pruned_at;
+
+-- name: DeletePrunedPostsBefore :execrows
+DELETE FROM pruned_posts
+WHERE pruned_at < $1;
+
+-- name: GetPrunedURLs :many
+-- Which of urls belong to pruned posts.
+SELECT url FROM pruned_posts
+WHERE url = ANY(sqlc.arg(urls)::text[]);
+
+-- name: GetPostsToSanitize :many
+-- Pages through posts by id, for re-running the sanitizer over the original
+-- content: all of them, or only those never sanitized.
+SELECT id, url, raw_description, raw_content
+FROM posts
+WHERE id > sqlc.arg(id)
+  AND (NOT sqlc.arg(unsanitized_only)::bool OR NOT sanitized)
+ORDER BY id
+LIMIT sqlc.arg('limit');
+
+-- name: SearchPosts :many
+-- Ranked full-text search, scoped to the user's followed feeds unless
+-- all_feeds is set. Snippets mark matches with **.
+SELECT
+  posts.id,
+  posts.title,
+  posts.url,
+  posts.published_at,
+  feeds.name AS feed_name,
+  ts_rank(posts.search, query)::real AS rank,
+  ts_headline(
+    'english',
+    COALESCE(NULLIF(posts.description, ''), posts.content, posts.title),
+    query,
+    'StartSel=**, StopSel=**, MaxFragments=2, MinWords=8, MaxWords=24'
+  )::text AS snippet
+FROM posts
+JOIN feeds ON feeds.id = posts.feed_id
+CROSS JOIN websearch_to_tsquery('english', sqlc.arg(query)::text) AS query
+WHERE posts.search @@ query
+  AND (
+    sqlc.arg(all_feeds)::bool
+    OR EXISTS (
+      SELECT 1 FROM feed_follows
+      WHERE feed_follows.feed_id = posts.feed_id
+        AND feed_follows.user_id = sqlc.arg(user_id)
+    )
+  )
+ORDER BY rank DESC, posts.published_at DESC NULLS LAST
+LIMIT sqlc.arg('limit');
+
+-- name: UpdatePostContent :exec
+UPDATE posts
+SET description = $2, content = $3, updated_at = $4, sanitized = TRUE
+WHERE id = $1;
+
+-- name: GetPostByID :one
+SELECT
+  posts.id, posts.created_at, posts.updated_at,
+  posts.title, posts.url, posts.description, posts.published_at,
+  posts.feed_id, posts.content,
+  feeds.name AS feed_name
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+WHERE posts.id = $1;
+
+-- name: GetPostsForFeed :many
+SELECT
+  posts.id, posts.created_at, posts.updated_at,
+  posts.title, posts.url, posts.description, posts.published_at,
+  posts.feed_id, posts.content,
+  feeds.name AS feed_name
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+WHERE posts.feed_id = $1
+ORDER BY posts.published_at DESC NULLS LAST, posts.created_at DESC, posts.id DESC
+LIMIT $2
+OFFSET $3;
+
+-- name: DeletePost :execrows
+DELETE FROM posts
+WHERE id = $1;
@@ -0,0 +1,17 @@
+-- name: GetPublishedFeed :one
+SELECT * FROM published_feeds
+WHERE user_id = $1;
+
+-- name: SetPublishedFeedSecret :one
+-- Creates the user's published feed, or gives it a new secret URL.
+INSERT INTO published_feeds (user_id, created_at, secret)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id) DO UPDATE
+SET created_at = EXCLUDED.created_at,
+    secret = EXCLUDED.secret
+RETURNING *;
+
+-- name: GetUserByPublishSecret :one
+SELECT users.* FROM users
+JOIN published_feeds ON published_feeds.user_id = users.id
+WHERE published_feeds.secret = $1;
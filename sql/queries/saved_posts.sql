@@ -0,0 +1,25 @@
+-- name: SavePost :one
+INSERT INTO saved_posts (
+  id, created_at, user_id, post_id,
+  feed_name, title, url, description, published_at
+)
+SELECT
+  sqlc.arg(id)::uuid, sqlc.arg(created_at)::timestamp, sqlc.arg(user_id)::uuid, posts.id,
+  feeds.name, posts.title, posts.url, posts.description, posts.published_at
+FROM posts
+JOIN feeds ON feeds.id = posts.feed_id
+WHERE posts.id = sqlc.arg(post_id)::uuid
+RETURNING *;
+
+-- name: DeleteSavedPost :execrows
+-- Accepts either the original post's ID or the saved copy's ID, since the
+-- original may be gone.
+DELETE FROM saved_posts
+WHERE user_id = sqlc.arg(user_id)
+  AND (post_id = sqlc.arg(id)::uuid OR id = sqlc.arg(id)::uuid);
+
+-- name: GetSavedPostsForUser :many
+SELECT * FROM saved_posts
+WHERE user_id = $1
+ORDER BY created_at DESC
+LIMIT $2;
@@ -21,3 +21,13 @@ DELETE FROM users;
 -- name: GetUsers :many
 SELECT * FROM users
 ORDER BY created_at;
+
+-- name: DeleteUser :execrows
+DELETE FROM users
+WHERE name = $1;
+
+-- name: SetUserEmail :one
+UPDATE users
+SET email = $2, updated_at = $3
+WHERE id = $1
+RETURNING *;
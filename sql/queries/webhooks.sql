@@ -0,0 +1,110 @@
+-- name: CreateWebhook :one
+INSERT INTO webhooks (id, created_at, user_id, feed_id, url, secret)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetWebhooksForUser :many
+SELECT
+  webhooks.id,
+  webhooks.created_at,
+  webhooks.feed_id,
+  webhooks.url,
+  feeds.name AS feed_name,
+  COUNT(webhook_deliveries.id) FILTER (WHERE webhook_deliveries.status = 'pending') AS pending,
+  COUNT(webhook_deliveries.id) FILTER (WHERE webhook_deliveries.status = 'delivered') AS delivered,
+  COUNT(webhook_deliveries.id) FILTER (WHERE webhook_deliveries.status = 'dead') AS dead
+FROM webhooks
+LEFT JOIN feeds ON feeds.id = webhooks.feed_id
+LEFT JOIN webhook_deliveries ON webhook_deliveries.webhook_id = webhooks.id
+WHERE webhooks.user_id = $1
+GROUP BY webhooks.id, feeds.name
+ORDER BY webhooks.created_at;
+
+-- name: DeleteWebhook :execrows
+DELETE FROM webhooks
+WHERE id = $1 AND user_id = $2;
+
+-- name: EnqueueWebhookDeliveries :execrows
+-- Queues each post for every webhook that wants it: those of users who
+-- follow the post's feed, unless they are limited to another feed.
+INSERT INTO webhook_deliveries (id, created_at, webhook_id, post_id, next_attempt_at)
+SELECT gen_random_uuid(), sqlc.arg(now)::timestamp, webhooks.id, posts.id, sqlc.arg(now)::timestamp
+FROM posts
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+JOIN webhooks ON webhooks.user_id = feed_follows.user_id
+WHERE posts.id = ANY(sqlc.arg(post_ids)::uuid[])
+  AND (webhooks.feed_id IS NULL OR webhooks.feed_id = posts.feed_id)
+ON CONFLICT (webhook_id, post_id) DO NOTHING;
+
+-- name: ClaimWebhookDeliveries :many
+-- Takes deliveries that are due and moves their next attempt to
+-- lease_until, so other workers leave them alone while they are sent.
+UPDATE webhook_deliveries
+SET next_attempt_at = sqlc.arg(lease_until)::timestamp
+FROM webhooks, posts, feeds
+WHERE webhook_deliveries.id IN (
+    SELECT id FROM webhook_deliveries
+    WHERE status = 'pending' AND next_attempt_at <= sqlc.arg(now)::timestamp
+    ORDER BY next_attempt_at
+    LIMIT sqlc.arg('limit')
+    FOR UPDATE SKIP LOCKED
+  )
+  AND webhooks.id = webhook_deliveries.webhook_id
+  AND posts.id = webhook_deliveries.post_id
+  AND feeds.id = posts.feed_id
+RETURNING
+  webhook_deliveries.id,
+  webhook_deliveries.attempts,
+  webhooks.id AS webhook_id,
+  webhooks.url AS webhook_url,
+  webhooks.secret,
+  posts.id AS post_id,
+  posts.created_at AS post_created_at,
+  posts.title,
+  posts.url,
+  posts.description,
+  posts.content,
+  posts.published_at,
+  posts.feed_id,
+  feeds.name AS feed_name;
+
+-- name: RecordWebhookAttempt :exec
+UPDATE webhook_deliveries
+SET status = sqlc.arg(status),
+  attempts = attempts + 1,
+  last_attempt_at = sqlc.arg(attempted_at)::timestamp,
+  last_status = sqlc.arg(last_status),
+  last_error = sqlc.arg(last_error),
+  next_attempt_at = sqlc.arg(next_attempt_at)::timestamp
+WHERE id = sqlc.arg(id);
+
+-- name: GetWebhookDeliveriesForUser :many
+-- The user's most recent deliveries, optionally only those with a status.
+SELECT
+  webhook_deliveries.id,
+  webhook_deliveries.created_at,
+  webhook_deliveries.webhook_id,
+  webhook_deliveries.status,
+  webhook_deliveries.attempts,
+  webhook_deliveries.next_attempt_at,
+  webhook_deliveries.last_attempt_at,
+  webhook_deliveries.last_status,
+  webhook_deliveries.last_error,
+  webhooks.url AS webhook_url,
+  posts.title AS post_title
+FROM webhook_deliveries
+JOIN webhooks ON webhooks.id = webhook_deliveries.webhook_id
+JOIN posts ON posts.id = webhook_deliveries.post_id
+WHERE webhooks.user_id = sqlc.arg(user_id)
+  AND (sqlc.arg(status)::text = '' OR webhook_deliveries.status = sqlc.arg(status))
+ORDER BY webhook_deliveries.created_at DESC
+LIMIT sqlc.arg('limit');
+
+-- name: RequeueDeadWebhookDeliveries :execrows
+-- Gives the dead deliveries of the user's webhooks a fresh set of attempts.
+UPDATE webhook_deliveries
+SET status = 'pending', attempts = 0, next_attempt_at = sqlc.arg(now)::timestamp
+FROM webhooks
+WHERE webhooks.id = webhook_deliveries.webhook_id
+  AND webhooks.user_id = sqlc.arg(user_id)
+  AND webhook_deliveries.status = 'dead';
@@ -0,0 +1,50 @@
+-- name: UpsertWebSubSubscription :one
+INSERT INTO websub_subscriptions (id, created_at, updated_at, feed_id, hub_url, topic_url, secret, state)
+VALUES ($1, $2, $3, $4, $5, $6, $7, 'pending')
+ON CONFLICT (feed_id) DO UPDATE
+SET hub_url = EXCLUDED.hub_url,
+    topic_url = EXCLUDED.topic_url,
+    secret = EXCLUDED.secret,
+    -- renewing an existing lease keeps it active until the hub re-verifies
+    state = CASE
+      WHEN websub_subscriptions.hub_url = EXCLUDED.hub_url
+        AND websub_subscriptions.topic_url = EXCLUDED.topic_url
+      THEN websub_subscriptions.state
+      ELSE 'pending'
+    END,
+    updated_at = EXCLUDED.updated_at
+RETURNING *;
+
+-- name: GetWebSubSubscription :one
+SELECT * FROM websub_subscriptions
+WHERE id = $1;
+
+-- name: GetWebSubSubscriptionByFeed :one
+SELECT * FROM websub_subscriptions
+WHERE feed_id = $1;
+
+-- name: ActivateWebSubSubscription :exec
+UPDATE websub_subscriptions
+SET state = 'active',
+    lease_expires_at = $2,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: SetWebSubSubscriptionState :exec
+UPDATE websub_subscriptions
+SET state = $2,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: GetWebSubSubscriptionsToRenew :many
+SELECT * FROM websub_subscriptions
+WHERE state = 'active' AND lease_expires_at < $1
+ORDER BY lease_expires_at ASC;
+
+-- name: HasActiveWebSubSubscription :one
+SELECT EXISTS (
+  SELECT 1 FROM websub_subscriptions
+  WHERE feed_id = sqlc.arg(feed_id)
+    AND state = 'active'
+    AND lease_expires_at > sqlc.arg(now)::timestamp
+) AS active;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- poll_interval is in seconds, recomputed by the scheduler after each scrape
+ALTER TABLE feeds
+ADD COLUMN poll_interval INTEGER;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN poll_interval;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- refresh_interval is in seconds; when set it overrides the scheduler
+ALTER TABLE feeds
+ADD COLUMN refresh_interval INTEGER;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN refresh_interval;
@@ -0,0 +1,18 @@
+-- +goose Up
+-- Saved posts keep their own copy of the article, so they survive the post
+-- being pruned or its feed being removed.
+CREATE TABLE saved_posts (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  post_id UUID REFERENCES posts(id) ON DELETE SET NULL,
+  feed_name TEXT NOT NULL,
+  title TEXT NOT NULL,
+  url TEXT NOT NULL,
+  description TEXT,
+  published_at TIMESTAMP,
+  UNIQUE(user_id, url)
+);
+
+-- +goose Down
+DROP TABLE saved_posts;
@@ -0,0 +1,21 @@
+-- +goose Up
+ALTER TABLE posts
+ADD COLUMN content TEXT;
+
+ALTER TABLE posts
+ADD COLUMN search TSVECTOR GENERATED ALWAYS AS (
+  setweight(to_tsvector('english', COALESCE(title, '')), 'A') ||
+  setweight(to_tsvector('english', COALESCE(description, '')), 'B') ||
+  setweight(to_tsvector('english', COALESCE(content, '')), 'C')
+) STORED;
+
+CREATE INDEX posts_search_idx ON posts USING GIN (search);
+
+-- +goose Down
+DROP INDEX posts_search_idx;
+
+ALTER TABLE posts
+DROP COLUMN search;
+
+ALTER TABLE posts
+DROP COLUMN content;
@@ -0,0 +1,12 @@
+-- +goose Up
+ALTER TABLE posts
+ADD COLUMN raw_description TEXT,
+ADD COLUMN raw_content TEXT;
+
+-- posts stored so far were never sanitized, so what we have is the original
+UPDATE posts SET raw_description = description, raw_content = content;
+
+-- +goose Down
+ALTER TABLE posts
+DROP COLUMN raw_content,
+DROP COLUMN raw_description;
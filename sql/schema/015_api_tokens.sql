@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE api_tokens (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name TEXT NOT NULL,
+  -- SHA-256 of the token; the token itself is only shown once
+  token_hash TEXT NOT NULL UNIQUE,
+  -- first characters of the token, to tell tokens apart
+  prefix TEXT NOT NULL,
+  last_used_at TIMESTAMP
+);
+
+CREATE INDEX api_tokens_user_id_idx ON api_tokens (user_id);
+
+-- +goose Down
+DROP TABLE api_tokens;
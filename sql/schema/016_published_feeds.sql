@@ -0,0 +1,10 @@
+-- +goose Up
+CREATE TABLE published_feeds (
+  user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+  created_at TIMESTAMP NOT NULL,
+  -- unguessable part of the feed's URL; readers can't send tokens
+  secret TEXT NOT NULL UNIQUE
+);
+
+-- +goose Down
+DROP TABLE published_feeds;
@@ -0,0 +1,14 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN email TEXT;
+
+-- posts already sent to a user in a digest
+CREATE TABLE digest_posts (
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+  sent_at TIMESTAMP NOT NULL,
+  PRIMARY KEY (user_id, post_id)
+);
+
+-- +goose Down
+DROP TABLE digest_posts;
+ALTER TABLE users DROP COLUMN email;
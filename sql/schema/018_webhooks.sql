@@ -0,0 +1,36 @@
+-- +goose Up
+CREATE TABLE webhooks (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  -- only posts from this feed; NULL for every feed the user follows
+  feed_id UUID REFERENCES feeds(id) ON DELETE CASCADE,
+  url TEXT NOT NULL,
+  -- HMAC-SHA256 key for the X-Gator-Signature header
+  secret TEXT NOT NULL
+);
+
+CREATE INDEX webhooks_user_id_idx ON webhooks (user_id);
+
+CREATE TABLE webhook_deliveries (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  webhook_id UUID NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+  post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+  -- pending, delivered, or dead once it has run out of attempts
+  status TEXT NOT NULL DEFAULT 'pending',
+  attempts INT NOT NULL DEFAULT 0,
+  next_attempt_at TIMESTAMP NOT NULL,
+  last_attempt_at TIMESTAMP,
+  -- HTTP status of the last attempt, if there was a response
+  last_status INT,
+  last_error TEXT,
+  UNIQUE (webhook_id, post_id)
+);
+
+CREATE INDEX webhook_deliveries_due_idx ON webhook_deliveries (next_attempt_at)
+  WHERE status = 'pending';
+
+-- +goose Down
+DROP TABLE webhook_deliveries;
+DROP TABLE webhooks;
@@ -0,0 +1,30 @@
+-- +goose Up
+ALTER TABLE posts ADD COLUMN author TEXT;
+
+CREATE TABLE alert_rules (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  -- only posts from this feed; NULL for every feed the user follows
+  feed_id UUID REFERENCES feeds(id) ON DELETE CASCADE,
+  -- keyword, regex or author
+  kind TEXT NOT NULL,
+  pattern TEXT NOT NULL,
+  -- whether agg announces matches as they happen
+  notify BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE INDEX alert_rules_user_id_idx ON alert_rules (user_id);
+
+CREATE TABLE alerts (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  rule_id UUID NOT NULL REFERENCES alert_rules(id) ON DELETE CASCADE,
+  post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+  UNIQUE (rule_id, post_id)
+);
+
+-- +goose Down
+DROP TABLE alerts;
+DROP TABLE alert_rules;
+ALTER TABLE posts DROP COLUMN author;
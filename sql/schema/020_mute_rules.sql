@@ -0,0 +1,19 @@
+-- +goose Up
+ALTER TABLE posts ADD COLUMN categories TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE TABLE mute_rules (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  -- only posts from this feed; NULL for every feed
+  feed_id UUID REFERENCES feeds(id) ON DELETE CASCADE,
+  -- title (a case-insensitive regex), category or author
+  kind TEXT NOT NULL,
+  pattern TEXT NOT NULL
+);
+
+CREATE INDEX mute_rules_user_id_idx ON mute_rules (user_id);
+
+-- +goose Down
+DROP TABLE mute_rules;
+ALTER TABLE posts DROP COLUMN categories;
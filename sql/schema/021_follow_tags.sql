@@ -0,0 +1,6 @@
+-- +goose Up
+-- folders the user files the feed under, kept sorted
+ALTER TABLE feed_follows ADD COLUMN tags TEXT[] NOT NULL DEFAULT '{}';
+
+-- +goose Down
+ALTER TABLE feed_follows DROP COLUMN tags;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- 014 kept the content of posts stored before sanitizing existed as it was.
+-- Flag those (and any stored since whose sanitized form is unchanged, which
+-- is harmless) so gator sanitizes them the next time it starts.
+ALTER TABLE posts ADD COLUMN sanitized BOOLEAN NOT NULL DEFAULT TRUE;
+
+UPDATE posts SET sanitized = FALSE
+WHERE (description IS NOT NULL OR content IS NOT NULL)
+  AND description IS NOT DISTINCT FROM raw_description
+  AND content IS NOT DISTINCT FROM raw_content;
+
+CREATE INDEX posts_unsanitized_idx ON posts (id) WHERE NOT sanitized;
+
+-- +goose Down
+ALTER TABLE posts DROP COLUMN sanitized;
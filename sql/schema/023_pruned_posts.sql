@@ -0,0 +1,11 @@
+-- +goose Up
+-- URLs of posts prune removed, so they aren't stored again as new while
+-- their feed still lists them
+CREATE TABLE pruned_posts (
+  url TEXT PRIMARY KEY,
+  feed_id UUID NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+  pruned_at TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE pruned_posts;
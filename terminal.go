@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// defaultTerminalWidth is used when the width can't be determined, e.g.
+// when output is piped.
+const defaultTerminalWidth = 80
+
+// terminalWidth returns the width in columns to wrap text to: $COLUMNS if
+// set, else what stty reports for the terminal, else 80.
+func terminalWidth() int {
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+	if _, cols, ok := terminalSize(); ok {
+		return cols
+	}
+	return defaultTerminalWidth
+}
+
+// terminalSize asks stty for the size of the terminal on stdin.
+func terminalSize() (rows, cols int, ok bool) {
+	out, err := stty("size")
+	if err != nil {
+		return 0, 0, false
+	}
+	fields := strings.Fields(out)
+	if len(fields) != 2 {
+		return 0, 0, false
+	}
+	rows, err1 := strconv.Atoi(fields[0])
+	cols, err2 := strconv.Atoi(fields[1])
+	if err1 != nil || err2 != nil || rows <= 0 || cols <= 0 {
+		return 0, 0, false
+	}
+	return rows, cols, true
+}
+
+// enterRawMode switches the terminal on stdin to raw mode without echo, so
+// keys are read one at a time, and returns a function that restores the
+// previous settings.
+func enterRawMode() (func(), error) {
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := stty("raw", "-echo"); err != nil {
+		return nil, err
+	}
+	return func() { stty(strings.TrimSpace(saved)) }, nil
+}
+
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return string(out), err
+}
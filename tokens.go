@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gator/internal/database"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// apiTokenPrefix marks gator API tokens, so they are easy to spot in config
+// files and secret scanners.
+const apiTokenPrefix = "gator_"
+
+// newAPIToken returns a new random token.
+func newAPIToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return apiTokenPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashAPIToken is what the database stores in place of a token. Tokens are
+// random, so a plain SHA-256 is enough.
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// userForAPIToken returns the user a token belongs to and records that it
+// was used. It returns sql.ErrNoRows for unknown tokens.
+func userForAPIToken(ctx context.Context, s *state, token string) (database.User, error) {
+	return s.db.GetUserByAPIToken(ctx, database.GetUserByAPITokenParams{
+		UsedAt:    time.Now(),
+		TokenHash: hashAPIToken(token),
+	})
+}
+
+func handlerAddToken(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 1 {
+		return errors.New("addtoken requires a name for the token")
+	}
+
+	token, err := newAPIToken()
+	if err != nil {
+		return err
+	}
+	created, err := s.db.CreateAPIToken(context.Background(), database.CreateAPITokenParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UserID:    user.ID,
+		Name:      cmd.args[0],
+		TokenHash: hashAPIToken(token),
+		Prefix:    token[:len(apiTokenPrefix)+6],
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("created token %s (%s) for %s\n", created.Name, created.ID, user.Name)
+	fmt.Println("it won't be shown again:")
+	fmt.Println(token)
+	return nil
+}
+
+func handlerTokens(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 0 {
+		return errors.New("tokens takes no arguments")
+	}
+
+	tokens, err := s.db.GetAPITokensForUser(context.Background(), user.ID)
+	if err != nil {
+		return err
+	}
+
+	l := newListing("id", "name", "prefix", "created_at", "last_used_at")
+	for _, t := range tokens {
+		l.add(t.ID, t.Name, t.Prefix, t.CreatedAt, t.LastUsedAt)
+	}
+	return render(s, l, func() {
+		for _, t := range tokens {
+			fmt.Printf("* %s (%s...)\n", t.Name, t.Prefix)
+			fmt.Printf("  id: %s\n", t.ID)
+			fmt.Printf("  created: %v\n", t.CreatedAt)
+			if t.LastUsedAt.Valid {
+				fmt.Printf("  last used: %v\n", t.LastUsedAt.Time)
+			} else {
+				fmt.Println("  never used")
+			}
+		}
+	})
+}
+
+func handlerRevokeToken(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 1 {
+		return errors.New("revoketoken requires a token id")
+	}
+	id, err := uuid.Parse(cmd.args[0])
+	if err != nil {
+		return fmt.Errorf("invalid token id: %s", cmd.args[0])
+	}
+
+	n, err := s.db.DeleteAPIToken(context.Background(), database.DeleteAPITokenParams{
+		ID:     id,
+		UserID: user.ID,
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("token not found: %s", id)
+	}
+
+	fmt.Println("token revoked")
+	return nil
+}
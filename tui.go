@@ -0,0 +1,563 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"gator/internal/database"
+	"gator/internal/markup"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// tuiPostLimit is how many posts the TUI loads for the selected feed.
+const tuiPostLimit = 200
+
+// tuiFar is a move large enough to reach the first or last row.
+const tuiFar = 1 << 30
+
+const (
+	ansiAltScreenOn  = "\x1b[?1049h"
+	ansiAltScreenOff = "\x1b[?1049l"
+	ansiHideCursor   = "\x1b[?25l"
+	ansiShowCursor   = "\x1b[?25h"
+	ansiReverse      = "\x1b[7m"
+	ansiBold         = "\x1b[1m"
+	ansiReset        = "\x1b[0m"
+)
+
+const tuiHelp = "j/k move  tab/h/l switch pane  enter read  r read/unread  s save  o open  u unread only  q quit"
+
+type tuiPane int
+
+const (
+	feedsPane tuiPane = iota
+	postsPane
+	readerPane
+)
+
+type tuiFeed struct {
+	name string
+	url  string // empty for all followed feeds
+}
+
+// tui is the state of the interactive reader: followed feeds on the left,
+// the selected feed's posts in the middle and the selected post on the
+// right.
+type tui struct {
+	s       *state
+	user    database.User
+	restore func()
+
+	feeds []tuiFeed
+	posts []database.GetPostsForUserRow
+	read  map[uuid.UUID]bool
+
+	focus      tuiPane
+	feed, post int // selected rows
+	feedTop    int // first visible row of each pane
+	postTop    int
+	readerTop  int
+	unreadOnly bool
+	status     string
+
+	rows, cols int
+}
+
+func handlerTUI(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 0 {
+		return errors.New("tui takes no arguments")
+	}
+
+	t := &tui{s: s, user: user, read: make(map[uuid.UUID]bool)}
+	if err := t.loadFeeds(); err != nil {
+		return err
+	}
+	if err := t.loadPosts(); err != nil {
+		return err
+	}
+
+	if err := t.enterScreen(); err != nil {
+		return fmt.Errorf("tui needs an interactive terminal: %w", err)
+	}
+	defer t.leaveScreen()
+
+	buf := make([]byte, 64)
+	for {
+		t.draw()
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return err
+		}
+		for _, key := range parseKeys(buf[:n]) {
+			t.status = ""
+			quit, err := t.handleKey(key)
+			if err != nil {
+				t.status = err.Error()
+			}
+			if quit {
+				return nil
+			}
+		}
+	}
+}
+
+func (t *tui) enterScreen() error {
+	restore, err := enterRawMode()
+	if err != nil {
+		return err
+	}
+	t.restore = restore
+	fmt.Print(ansiAltScreenOn + ansiHideCursor)
+	return nil
+}
+
+func (t *tui) leaveScreen() {
+	fmt.Print(ansiReset + ansiShowCursor + ansiAltScreenOff)
+	t.restore()
+}
+
+func (t *tui) loadFeeds() error {
+	follows, err := t.s.db.GetFeedFollowsForUser(context.Background(), t.user.ID)
+	if err != nil {
+		return err
+	}
+
+	t.feeds = []tuiFeed{{name: "All feeds"}}
+	for _, f := range follows {
+		t.feeds = append(t.feeds, tuiFeed{name: f.FeedName, url: f.FeedUrl})
+	}
+	return nil
+}
+
+// loadPosts loads the posts of the selected feed and which of them are read.
+func (t *tui) loadPosts() error {
+	filters := postFilters{unreadOnly: t.unreadOnly}
+	if url := t.feeds[t.feed].url; url != "" {
+		filters.feeds = []string{url}
+	}
+	params := newPostQuery(t.user.ID, tuiPostLimit)
+	if err := filters.apply(&params); err != nil {
+		return err
+	}
+	posts, err := params.fetch(context.Background(), t.s.db)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]uuid.UUID, len(posts))
+	for i, p := range posts {
+		ids[i] = p.ID
+	}
+	read, err := t.s.db.GetReadPostIDs(context.Background(), database.GetReadPostIDsParams{
+		UserID:  t.user.ID,
+		PostIds: ids,
+	})
+	if err != nil {
+		return err
+	}
+
+	t.posts = posts
+	clear(t.read)
+	for _, id := range read {
+		t.read[id] = true
+	}
+	t.post, t.postTop, t.readerTop = 0, 0, 0
+	return nil
+}
+
+func (t *tui) selectedPost() (database.GetPostsForUserRow, bool) {
+	if t.post >= len(t.posts) {
+		return database.GetPostsForUserRow{}, false
+	}
+	return t.posts[t.post], true
+}
+
+// handleKey applies one key press and reports whether to quit.
+func (t *tui) handleKey(key string) (bool, error) {
+	switch key {
+	case "q", "ctrl-c":
+		return true, nil
+	case "tab", "l", "right":
+		if t.focus < readerPane {
+			t.focus++
+		}
+	case "h", "left", "shift-tab", "esc", "backspace":
+		if t.focus > feedsPane {
+			t.focus--
+		}
+	case "j", "down":
+		return false, t.move(1)
+	case "k", "up":
+		return false, t.move(-1)
+	case "pgdn", " ":
+		return false, t.move(t.bodyHeight() - 1)
+	case "pgup", "b":
+		return false, t.move(-(t.bodyHeight() - 1))
+	case "g", "home":
+		return false, t.move(-tuiFar)
+	case "G", "end":
+		return false, t.move(tuiFar)
+	case "enter":
+		switch t.focus {
+		case feedsPane:
+			t.focus = postsPane
+		case postsPane:
+			t.focus = readerPane
+			return false, t.setRead(true)
+		}
+	case "r":
+		p, ok := t.selectedPost()
+		if !ok {
+			return false, nil
+		}
+		return false, t.setRead(!t.read[p.ID])
+	case "s":
+		return false, t.save()
+	case "o":
+		return false, t.openInBrowser()
+	case "u":
+		t.unreadOnly = !t.unreadOnly
+		return false, t.loadPosts()
+	}
+	return false, nil
+}
+
+// move moves the selection of the focused pane, or scrolls the reader.
+func (t *tui) move(delta int) error {
+	switch t.focus {
+	case feedsPane:
+		next := clamp(t.feed+delta, 0, len(t.feeds)-1)
+		if next == t.feed {
+			return nil
+		}
+		t.feed = next
+		return t.loadPosts()
+	case postsPane:
+		t.post = clamp(t.post+delta, 0, max(len(t.posts)-1, 0))
+		t.readerTop = 0
+	case readerPane:
+		t.readerTop = clamp(t.readerTop+delta, 0, max(len(t.readerLines())-t.bodyHeight(), 0))
+	}
+	return nil
+}
+
+func (t *tui) setRead(read bool) error {
+	p, ok := t.selectedPost()
+	if !ok || t.read[p.ID] == read {
+		return nil
+	}
+
+	var err error
+	if read {
+		_, err = t.s.db.MarkPostsRead(context.Background(), database.MarkPostsReadParams{
+			UserID:  t.user.ID,
+			ReadAt:  time.Now(),
+			PostIds: []uuid.UUID{p.ID},
+		})
+	} else {
+		_, err = t.s.db.MarkPostsUnread(context.Background(), database.MarkPostsUnreadParams{
+			UserID:  t.user.ID,
+			PostIds: []uuid.UUID{p.ID},
+		})
+	}
+	if err != nil {
+		return err
+	}
+	t.read[p.ID] = read
+	return nil
+}
+
+func (t *tui) save() error {
+	p, ok := t.selectedPost()
+	if !ok {
+		return nil
+	}
+	_, err := t.s.db.SavePost(context.Background(), database.SavePostParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UserID:    t.user.ID,
+		PostID:    p.ID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.New("post no longer exists")
+		}
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return errors.New("post is already saved")
+		}
+		return err
+	}
+	t.status = "saved " + p.Title
+	return nil
+}
+
+// openInBrowser runs $BROWSER on the selected post's URL. The terminal is
+// handed over while it runs, so text-mode browsers work too.
+func (t *tui) openInBrowser() error {
+	p, ok := t.selectedPost()
+	if !ok {
+		return nil
+	}
+	browser := os.Getenv("BROWSER")
+	if browser == "" {
+		return errors.New("set $BROWSER to open posts")
+	}
+
+	// $BROWSER may list several commands separated by ':'; use the first.
+	// A %s in it stands for the URL.
+	args := strings.Fields(strings.Split(browser, ":")[0])
+	if len(args) == 0 {
+		return errors.New("set $BROWSER to open posts")
+	}
+	if strings.Contains(browser, "%s") {
+		for i := range args {
+			args[i] = strings.ReplaceAll(args[i], "%s", p.Url)
+		}
+	} else {
+		args = append(args, p.Url)
+	}
+
+	t.leaveScreen()
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	runErr := cmd.Run()
+	if err := t.enterScreen(); err != nil {
+		return err
+	}
+	if runErr != nil {
+		return fmt.Errorf("%s: %w", args[0], runErr)
+	}
+	return t.setRead(true)
+}
+
+// layout returns the widths of the three panes.
+func (t *tui) layout() (feeds, posts, reader int) {
+	feeds = max(t.cols/5, 12)
+	posts = max(t.cols*7/20, 20)
+	reader = t.cols - feeds - posts - 2 // two separators
+	return feeds, posts, reader
+}
+
+// bodyHeight is the number of rows for pane contents, below the titles and
+// above the status line.
+func (t *tui) bodyHeight() int {
+	return max(t.rows-2, 1)
+}
+
+func (t *tui) readerLines() []string {
+	p, ok := t.selectedPost()
+	if !ok {
+		return nil
+	}
+	_, _, width := t.layout()
+	width = max(width-1, 10) // leave a margin after the separator
+
+	var lines []string
+	lines = append(lines, strings.Split(markup.ToText("<h1>"+escapeText(p.Title)+"</h1>", width), "\n")...)
+	lines = append(lines, p.FeedName)
+	if p.PublishedAt.Valid {
+		lines = append(lines, fromTimestamp(p.PublishedAt.Time).Format("Mon, 02 Jan 2006 15:04"))
+	}
+	lines = append(lines, p.Url, "")
+
+	body := p.Content
+	if !body.Valid {
+		body = p.Description
+	}
+	if body.Valid {
+		lines = append(lines, strings.Split(markup.ToText(body.String, width), "\n")...)
+	}
+	return lines
+}
+
+func (t *tui) draw() {
+	if rows, cols, ok := terminalSize(); ok {
+		t.rows, t.cols = rows, cols
+	} else {
+		t.rows, t.cols = 24, defaultTerminalWidth
+	}
+
+	var b strings.Builder
+	b.WriteString("\x1b[H")
+	if t.cols < 50 || t.rows < 5 {
+		b.WriteString("\x1b[2J\x1b[Hterminal too small")
+		os.Stdout.WriteString(b.String())
+		return
+	}
+
+	feedsW, postsW, readerW := t.layout()
+	height := t.bodyHeight()
+	t.feedTop = scrollTo(t.feed, t.feedTop, height)
+	t.postTop = scrollTo(t.post, t.postTop, height)
+	reader := t.readerLines()
+
+	postsTitle := "Posts"
+	if t.unreadOnly {
+		postsTitle = "Unread posts"
+	}
+	titles := []string{
+		t.paneTitle(feedsPane, "Feeds", feedsW),
+		t.paneTitle(postsPane, postsTitle, postsW),
+		t.paneTitle(readerPane, "Post", readerW),
+	}
+	writeRow(&b, 1, strings.Join(titles, "│"))
+
+	for y := 0; y < height; y++ {
+		var row strings.Builder
+
+		i := t.feedTop + y
+		if i < len(t.feeds) {
+			row.WriteString(t.cell(feedsPane, i == t.feed, " "+t.feeds[i].name, feedsW))
+		} else {
+			row.WriteString(strings.Repeat(" ", feedsW))
+		}
+		row.WriteString("│")
+
+		i = t.postTop + y
+		if i < len(t.posts) {
+			mark := "● "
+			if t.read[t.posts[i].ID] {
+				mark = "  "
+			}
+			row.WriteString(t.cell(postsPane, i == t.post, mark+t.posts[i].Title, postsW))
+		} else if y == 0 && len(t.posts) == 0 {
+			row.WriteString(fit(" no posts", postsW))
+		} else {
+			row.WriteString(strings.Repeat(" ", postsW))
+		}
+		row.WriteString("│")
+
+		i = t.readerTop + y
+		line := ""
+		if i < len(reader) {
+			line = " " + reader[i]
+		}
+		row.WriteString(fit(line, readerW))
+
+		writeRow(&b, y+2, row.String())
+	}
+
+	status := t.status
+	if status == "" {
+		status = tuiHelp
+	}
+	writeRow(&b, t.rows, ansiReverse+fit(" "+status, t.cols)+ansiReset)
+	os.Stdout.WriteString(b.String())
+}
+
+func (t *tui) paneTitle(pane tuiPane, title string, width int) string {
+	if pane == t.focus {
+		return ansiReverse + ansiBold + fit(" "+title, width) + ansiReset
+	}
+	return ansiBold + fit(" "+title, width) + ansiReset
+}
+
+// cell renders one row of a list pane, highlighting the selected row.
+func (t *tui) cell(pane tuiPane, selected bool, text string, width int) string {
+	switch {
+	case selected && pane == t.focus:
+		return ansiReverse + fit(text, width) + ansiReset
+	case selected:
+		return ansiBold + fit(text, width) + ansiReset
+	}
+	return fit(text, width)
+}
+
+func writeRow(b *strings.Builder, row int, text string) {
+	fmt.Fprintf(b, "\x1b[%d;1H%s", row, text)
+}
+
+// fit pads or truncates text to exactly width columns, dropping control
+// characters that would move the cursor.
+func fit(text string, width int) string {
+	text = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, text)
+	n := utf8.RuneCountInString(text)
+	if n > width {
+		return string([]rune(text)[:max(width-1, 0)]) + "…"
+	}
+	return text + strings.Repeat(" ", width-n)
+}
+
+// scrollTo returns the first visible row that keeps selected on screen.
+func scrollTo(selected, top, height int) int {
+	if selected < top {
+		return selected
+	}
+	if selected >= top+height {
+		return selected - height + 1
+	}
+	return top
+}
+
+func clamp(n, lo, hi int) int {
+	return min(max(n, lo), hi)
+}
+
+// escapeText escapes plain text for passing through markup.ToText.
+func escapeText(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// parseKeys splits raw terminal input into key names: printable characters
+// as themselves, and names like "up", "enter" or "ctrl-c" for the rest.
+func parseKeys(input []byte) []string {
+	var keys []string
+	for i := 0; i < len(input); {
+		c := input[i]
+		switch {
+		case c == 0x1b && i+1 < len(input) && (input[i+1] == '[' || input[i+1] == 'O'):
+			// CSI or SS3 sequence: parameters, then a final byte
+			j := i + 2
+			for j < len(input) && (input[j] < 0x40 || input[j] > 0x7e) {
+				j++
+			}
+			if j == len(input) {
+				return keys
+			}
+			if key, ok := escapeKeys[string(input[i+2:j+1])]; ok {
+				keys = append(keys, key)
+			}
+			i = j + 1
+			continue
+		case c == 0x1b:
+			keys = append(keys, "esc")
+		case c == '\r' || c == '\n':
+			keys = append(keys, "enter")
+		case c == '\t':
+			keys = append(keys, "tab")
+		case c == 0x03:
+			keys = append(keys, "ctrl-c")
+		case c == 0x7f || c == 0x08:
+			keys = append(keys, "backspace")
+		default:
+			r, size := utf8.DecodeRune(input[i:])
+			if unicode.IsPrint(r) {
+				keys = append(keys, string(r))
+			}
+			i += size
+			continue
+		}
+		i++
+	}
+	return keys
+}
+
+var escapeKeys = map[string]string{
+	"A": "up", "B": "down", "C": "right", "D": "left",
+	"H": "home", "F": "end", "1~": "home", "7~": "home", "4~": "end", "8~": "end",
+	"5~": "pgup", "6~": "pgdn", "Z": "shift-tab",
+}
@@ -0,0 +1,580 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"gator/internal/database"
+	"html/template"
+	"io/fs"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+//go:embed web
+var webFiles embed.FS
+
+const (
+	webTokenCookie = "gator_token"
+	webPageSize    = 30
+)
+
+// webTemplates holds each page of the web reader, parsed together with the
+// layout they share.
+var webTemplates = parseWebTemplates("login.html", "river.html", "feeds.html")
+
+func parseWebTemplates(pages ...string) map[string]*template.Template {
+	templates := make(map[string]*template.Template, len(pages))
+	for _, page := range pages {
+		templates[page] = template.Must(template.ParseFS(webFiles, "web/templates/layout.html", "web/templates/"+page))
+	}
+	return templates
+}
+
+// webUI is the HTML reader `serve` offers next to the API. Visitors log in
+// with one of their API tokens, which is then kept in a cookie. Every
+// change is a POST, and the cookie is SameSite, so other sites can't make
+// them on a visitor's behalf.
+type webUI struct {
+	s *state
+}
+
+func newWebUI(s *state) *webUI {
+	return &webUI{s: s}
+}
+
+func (ui *webUI) register(mux *http.ServeMux) {
+	static, err := fs.Sub(webFiles, "web/static")
+	if err != nil {
+		panic(err)
+	}
+	mux.Handle("GET /static/", http.StripPrefix("/static/", http.FileServerFS(static)))
+	mux.HandleFunc("GET /login", ui.loginPage)
+	mux.HandleFunc("POST /login", ui.login)
+	mux.HandleFunc("POST /logout", ui.logout)
+	mux.HandleFunc("GET /{$}", ui.authenticated(ui.river))
+	mux.HandleFunc("POST /posts/read", ui.authenticated(ui.markRead))
+	mux.HandleFunc("POST /posts/unread", ui.authenticated(ui.markUnread))
+	mux.HandleFunc("GET /feeds", ui.authenticated(ui.feeds))
+	mux.HandleFunc("POST /feeds", ui.authenticated(ui.addFeed))
+	mux.HandleFunc("POST /feeds/{id}/follow", ui.authenticated(ui.follow))
+	mux.HandleFunc("POST /feeds/{id}/unfollow", ui.authenticated(ui.unfollow))
+	mux.HandleFunc("POST /feeds/{id}/delete", ui.authenticated(ui.deleteFeed))
+	mux.HandleFunc("POST /feeds/{id}/read", ui.authenticated(ui.markFeedRead))
+}
+
+// authenticated resolves the user from the token cookie, sending visitors
+// without a valid one to the login page.
+func (ui *webUI) authenticated(
+	handler func(w http.ResponseWriter, r *http.Request, user database.User),
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie(webTokenCookie)
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		user, err := userForAPIToken(r.Context(), ui.s, cookie.Value)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				// the token was revoked
+				clearTokenCookie(w)
+				http.Redirect(w, r, "/login", http.StatusSeeOther)
+				return
+			}
+			webInternalError(w, err)
+			return
+		}
+		handler(w, r, user)
+	}
+}
+
+// webPage is what the layout needs from every page.
+type webPage struct {
+	Title string
+	User  *database.User
+	Error string
+}
+
+type webFeed struct {
+	ID        uuid.UUID
+	Name      string
+	URL       string
+	AddedBy   string
+	Link      string // the feed's posts
+	Unread    int64
+	Following bool
+	Owned     bool
+	Current   bool
+}
+
+type webPost struct {
+	ID       uuid.UUID
+	Title    string
+	URL      string
+	FeedName string
+	Date     string
+	Body     template.HTML
+	Read     bool
+}
+
+type loginPage struct {
+	webPage
+}
+
+type riverPage struct {
+	webPage
+	Feeds      []webFeed
+	Feed       *webFeed // the feed shown, or nil for all
+	Unread     int64
+	UnreadOnly bool
+	Posts      []webPost
+	PageUnread bool   // some of Posts are unread
+	Self       string // where actions return to
+	AllLink    string
+	ToggleLink string
+	Newer      string
+	Older      string
+}
+
+type feedsPage struct {
+	webPage
+	Feeds []webFeed
+}
+
+func (ui *webUI) loginPage(w http.ResponseWriter, r *http.Request) {
+	ui.render(w, http.StatusOK, "login.html", loginPage{webPage{Title: "Log in"}})
+}
+
+func (ui *webUI) login(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimSpace(r.PostFormValue("token"))
+	if _, err := userForAPIToken(r.Context(), ui.s, token); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ui.render(w, http.StatusUnauthorized, "login.html", loginPage{webPage{Title: "Log in", Error: "Unknown token."}})
+			return
+		}
+		webInternalError(w, err)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     webTokenCookie,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int((30 * 24 * time.Hour).Seconds()),
+		HttpOnly: true,
+		Secure:   requestScheme(r) == "https",
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+func (ui *webUI) logout(w http.ResponseWriter, r *http.Request) {
+	clearTokenCookie(w)
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+func clearTokenCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{Name: webTokenCookie, Path: "/", MaxAge: -1, HttpOnly: true})
+}
+
+// river is the paginated list of posts from the feeds the user follows,
+// newest first, optionally for a single feed or only unread posts.
+func (ui *webUI) river(w http.ResponseWriter, r *http.Request, user database.User) {
+	q := r.URL.Query()
+	page := riverPage{
+		webPage:    webPage{Title: "Posts", User: &user},
+		UnreadOnly: q.Get("unread") == "1",
+		Self:       r.URL.RequestURI(),
+	}
+	feedURL := q.Get("feed")
+
+	counts, err := ui.s.db.GetUnreadCountsForUser(r.Context(), user.ID)
+	if err != nil {
+		webInternalError(w, err)
+		return
+	}
+	for _, c := range counts {
+		f := webFeed{
+			ID:        c.ID,
+			Name:      c.Name,
+			URL:       c.Url,
+			Link:      riverURL(c.Url, page.UnreadOnly, "", ""),
+			Unread:    c.Unread,
+			Following: true,
+			Current:   c.Url == feedURL,
+		}
+		page.Feeds = append(page.Feeds, f)
+		page.Unread += c.Unread
+	}
+	for i := range page.Feeds {
+		if page.Feeds[i].Current {
+			page.Feed = &page.Feeds[i]
+			page.Title = page.Feeds[i].Name
+		}
+	}
+	if feedURL != "" && page.Feed == nil {
+		http.NotFound(w, r)
+		return
+	}
+	page.AllLink = riverURL("", page.UnreadOnly, "", "")
+	page.ToggleLink = riverURL(feedURL, !page.UnreadOnly, "", "")
+
+	filters := postFilters{unreadOnly: page.UnreadOnly}
+	if feedURL != "" {
+		filters.feeds = []string{feedURL}
+	}
+	paging := pageOptions{before: q.Get("before"), after: q.Get("after"), page: 1}
+	params := newPostQuery(user.ID, webPageSize)
+	if err := filters.apply(&params); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := paging.apply(&params); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	posts, err := params.fetch(r.Context(), ui.s.db)
+	if err != nil {
+		webInternalError(w, err)
+		return
+	}
+	if params.CursorDirection == "after" {
+		slices.Reverse(posts)
+	}
+
+	ids := make([]uuid.UUID, len(posts))
+	for i, p := range posts {
+		ids[i] = p.ID
+	}
+	read, err := ui.s.db.GetReadPostIDs(r.Context(), database.GetReadPostIDsParams{
+		UserID:  user.ID,
+		PostIds: ids,
+	})
+	if err != nil {
+		webInternalError(w, err)
+		return
+	}
+
+	for _, p := range posts {
+		date := p.CreatedAt
+		if p.PublishedAt.Valid {
+			date = p.PublishedAt.Time
+		}
+		isRead := slices.Contains(read, p.ID)
+		page.Posts = append(page.Posts, webPost{
+			ID:       p.ID,
+			Title:    p.Title,
+			URL:      p.Url,
+			FeedName: p.FeedName,
+			Date:     fromTimestamp(date).Format("Jan 2, 2006 15:04"),
+			// sanitized when the post was stored, or when serve started for
+			// posts older than the sanitizer
+			Body: template.HTML(postBody(p)),
+			Read: isRead,
+		})
+		page.PageUnread = page.PageUnread || !isRead
+	}
+
+	newer, older := pageCursors(posts, params)
+	if newer != "" {
+		page.Newer = riverURL(feedURL, page.UnreadOnly, "after", newer)
+	}
+	if older != "" {
+		page.Older = riverURL(feedURL, page.UnreadOnly, "before", older)
+	}
+	ui.render(w, http.StatusOK, "river.html", page)
+}
+
+// riverURL links to a page of the river.
+func riverURL(feedURL string, unreadOnly bool, cursorKey, cursor string) string {
+	v := url.Values{}
+	if feedURL != "" {
+		v.Set("feed", feedURL)
+	}
+	if unreadOnly {
+		v.Set("unread", "1")
+	}
+	if cursorKey != "" {
+		v.Set(cursorKey, cursor)
+	}
+	if len(v) == 0 {
+		return "/"
+	}
+	return "/?" + v.Encode()
+}
+
+func (ui *webUI) markRead(w http.ResponseWriter, r *http.Request, user database.User) {
+	ids, ok := formPostIDs(w, r)
+	if !ok {
+		return
+	}
+	_, err := ui.s.db.MarkPostsRead(r.Context(), database.MarkPostsReadParams{
+		UserID:  user.ID,
+		ReadAt:  time.Now(),
+		PostIds: ids,
+	})
+	if err != nil {
+		webInternalError(w, err)
+		return
+	}
+	redirectBack(w, r)
+}
+
+func (ui *webUI) markUnread(w http.ResponseWriter, r *http.Request, user database.User) {
+	ids, ok := formPostIDs(w, r)
+	if !ok {
+		return
+	}
+	_, err := ui.s.db.MarkPostsUnread(r.Context(), database.MarkPostsUnreadParams{
+		UserID:  user.ID,
+		PostIds: ids,
+	})
+	if err != nil {
+		webInternalError(w, err)
+		return
+	}
+	redirectBack(w, r)
+}
+
+func (ui *webUI) markFeedRead(w http.ResponseWriter, r *http.Request, user database.User) {
+	feed, ok := ui.feed(w, r)
+	if !ok {
+		return
+	}
+	_, err := ui.s.db.MarkFeedPostsRead(r.Context(), database.MarkFeedPostsReadParams{
+		UserID: user.ID,
+		ReadAt: time.Now(),
+		FeedID: feed.ID,
+	})
+	if err != nil {
+		webInternalError(w, err)
+		return
+	}
+	redirectBack(w, r)
+}
+
+func (ui *webUI) feeds(w http.ResponseWriter, r *http.Request, user database.User) {
+	ui.renderFeeds(w, r, user, http.StatusOK, "")
+}
+
+// renderFeeds shows every feed, with the ones the user follows first.
+func (ui *webUI) renderFeeds(w http.ResponseWriter, r *http.Request, user database.User, status int, msg string) {
+	all, err := ui.s.db.GetFeeds(r.Context())
+	if err != nil {
+		webInternalError(w, err)
+		return
+	}
+	counts, err := ui.s.db.GetUnreadCountsForUser(r.Context(), user.ID)
+	if err != nil {
+		webInternalError(w, err)
+		return
+	}
+	unread := make(map[uuid.UUID]int64, len(counts))
+	for _, c := range counts {
+		unread[c.ID] = c.Unread
+	}
+
+	page := feedsPage{webPage: webPage{Title: "Feeds", User: &user, Error: msg}}
+	for _, f := range all {
+		n, following := unread[f.ID]
+		page.Feeds = append(page.Feeds, webFeed{
+			ID:        f.ID,
+			Name:      f.Name,
+			URL:       f.Url,
+			AddedBy:   f.UserName,
+			Link:      riverURL(f.Url, false, "", ""),
+			Unread:    n,
+			Following: following,
+			Owned:     f.UserID == user.ID,
+		})
+	}
+	slices.SortStableFunc(page.Feeds, func(a, b webFeed) int {
+		switch {
+		case a.Following == b.Following:
+			return 0
+		case a.Following:
+			return -1
+		default:
+			return 1
+		}
+	})
+	ui.render(w, status, "feeds.html", page)
+}
+
+// addFeed adds a feed owned by the user, who follows it, like addfeed.
+func (ui *webUI) addFeed(w http.ResponseWriter, r *http.Request, user database.User) {
+	name := strings.TrimSpace(r.PostFormValue("name"))
+	feedURL := strings.TrimSpace(r.PostFormValue("url"))
+	if name == "" || feedURL == "" {
+		ui.renderFeeds(w, r, user, http.StatusBadRequest, "A feed needs a name and a URL.")
+		return
+	}
+	if u, err := url.Parse(feedURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		ui.renderFeeds(w, r, user, http.StatusBadRequest, fmt.Sprintf("Not an http(s) URL: %s", feedURL))
+		return
+	}
+
+	now := time.Now()
+	feed, err := ui.s.db.CreateFeed(r.Context(), database.CreateFeedParams{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Name:      name,
+		Url:       feedURL,
+		UserID:    user.ID,
+	})
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			ui.renderFeeds(w, r, user, http.StatusConflict, fmt.Sprintf("Feed URL already exists: %s", feedURL))
+			return
+		}
+		webInternalError(w, err)
+		return
+	}
+	if err := ui.followFeed(r, user, feed); err != nil {
+		webInternalError(w, err)
+		return
+	}
+	http.Redirect(w, r, "/feeds", http.StatusSeeOther)
+}
+
+func (ui *webUI) follow(w http.ResponseWriter, r *http.Request, user database.User) {
+	feed, ok := ui.feed(w, r)
+	if !ok {
+		return
+	}
+	if err := ui.followFeed(r, user, feed); err != nil {
+		webInternalError(w, err)
+		return
+	}
+	redirectBack(w, r)
+}
+
+// followFeed makes user follow feed, if they don't already.
+func (ui *webUI) followFeed(r *http.Request, user database.User, feed database.Feed) error {
+	now := time.Now()
+	_, err := ui.s.db.CreateFeedFollow(r.Context(), database.CreateFeedFollowParams{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		UserID:    user.ID,
+		FeedID:    feed.ID,
+	})
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		return nil
+	}
+	return err
+}
+
+func (ui *webUI) unfollow(w http.ResponseWriter, r *http.Request, user database.User) {
+	feed, ok := ui.feed(w, r)
+	if !ok {
+		return
+	}
+	err := ui.s.db.DeleteFeedFollow(r.Context(), database.DeleteFeedFollowParams{
+		UserID: user.ID,
+		FeedID: feed.ID,
+	})
+	if err != nil {
+		webInternalError(w, err)
+		return
+	}
+	redirectBack(w, r)
+}
+
+// deleteFeed deletes a feed the user added.
+func (ui *webUI) deleteFeed(w http.ResponseWriter, r *http.Request, user database.User) {
+	feed, ok := ui.feed(w, r)
+	if !ok {
+		return
+	}
+	if feed.UserID != user.ID {
+		ui.renderFeeds(w, r, user, http.StatusForbidden, "Only the user who added a feed can delete it.")
+		return
+	}
+	if _, err := ui.s.db.DeleteFeed(r.Context(), feed.ID); err != nil {
+		webInternalError(w, err)
+		return
+	}
+	redirectBack(w, r)
+}
+
+// feed loads the feed whose id is in the path, writing a 404 if there is
+// none.
+func (ui *webUI) feed(w http.ResponseWriter, r *http.Request) (database.Feed, bool) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.NotFound(w, r)
+		return database.Feed{}, false
+	}
+	feed, err := ui.s.db.GetFeedByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.NotFound(w, r)
+		} else {
+			webInternalError(w, err)
+		}
+		return database.Feed{}, false
+	}
+	return feed, true
+}
+
+// formPostIDs reads the post ids of a form, writing a 400 if one is
+// invalid.
+func formPostIDs(w http.ResponseWriter, r *http.Request) ([]uuid.UUID, bool) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	ids := make([]uuid.UUID, 0, len(r.PostForm["id"]))
+	for _, v := range r.PostForm["id"] {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid post id: %s", v), http.StatusBadRequest)
+			return nil, false
+		}
+		ids = append(ids, id)
+	}
+	return ids, true
+}
+
+// redirectBack sends the browser to the page a form came from. Only paths
+// on this site are followed.
+func redirectBack(w http.ResponseWriter, r *http.Request) {
+	next := r.PostFormValue("next")
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		next = "/"
+	}
+	http.Redirect(w, r, next, http.StatusSeeOther)
+}
+
+func (ui *webUI) render(w http.ResponseWriter, status int, page string, data any) {
+	var buf bytes.Buffer
+	if err := webTemplates[page].ExecuteTemplate(&buf, "layout", data); err != nil {
+		webInternalError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	// post bodies may link images from anywhere, but never run anything
+	w.Header().Set("Content-Security-Policy", "default-src 'self'; img-src http: https: data:; frame-ancestors 'none'")
+	w.WriteHeader(status)
+	if _, err := buf.WriteTo(w); err != nil {
+		log.Printf("web: error writing response: %v", err)
+	}
+}
+
+func webInternalError(w http.ResponseWriter, err error) {
+	log.Printf("web: %v", err)
+	http.Error(w, "internal error", http.StatusInternalServerError)
+}
@@ -0,0 +1,476 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gator/internal/database"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	webhookEvent = "post.created"
+
+	// deliveries sent at once
+	webhookBatchSize = 20
+	// a delivery is dead after this many failed attempts
+	webhookMaxAttempts = 8
+	// wait before the first retry; it doubles with every attempt after
+	webhookRetryBase = time.Minute
+	// the longest Retry-After a webhook can ask for
+	webhookMaxRetryAfter = 24 * time.Hour
+	// a claimed delivery whose worker died is retried after this long
+	webhookLease = 2 * time.Minute
+	// how often agg looks for due deliveries
+	webhookPollInterval = 5 * time.Second
+)
+
+// webhookPayload is the JSON body POSTed to webhooks.
+type webhookPayload struct {
+	Event      string    `json:"event"`
+	DeliveryID uuid.UUID `json:"delivery_id"`
+	WebhookID  uuid.UUID `json:"webhook_id"`
+	Post       apiPost   `json:"post"`
+}
+
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate secret: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// signWebhook returns the X-Gator-Signature of body: an HMAC-SHA256 keyed
+// with the webhook's secret, in the format GitHub uses.
+func signWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// enqueueWebhooks queues deliveries of new posts to the webhooks that want
+// them. The agg worker or deliverwebhooks sends them.
+func enqueueWebhooks(s *state, postIDs []uuid.UUID) {
+	if len(postIDs) == 0 {
+		return
+	}
+	_, err := s.db.EnqueueWebhookDeliveries(context.Background(), database.EnqueueWebhookDeliveriesParams{
+		Now:     time.Now(),
+		PostIds: postIDs,
+	})
+	if err != nil {
+		log.Printf("error queueing webhook deliveries: %v", err)
+	}
+}
+
+// webhookSender sends queued webhook deliveries. Several senders, in one
+// process or many, can share the queue.
+type webhookSender struct {
+	s      *state
+	client *http.Client
+}
+
+func newWebhookSender(s *state) *webhookSender {
+	return &webhookSender{
+		s:      s,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// run delivers due webhooks forever, for agg.
+func (ws *webhookSender) run() {
+	for {
+		if _, _, err := ws.deliverDue(context.Background()); err != nil {
+			log.Printf("error delivering webhooks: %v", err)
+		}
+		time.Sleep(webhookPollInterval)
+	}
+}
+
+// deliverDue sends every delivery that is due and returns how many
+// succeeded and failed.
+func (ws *webhookSender) deliverDue(ctx context.Context) (delivered, failed int, err error) {
+	for {
+		now := time.Now()
+		batch, err := ws.s.db.ClaimWebhookDeliveries(ctx, database.ClaimWebhookDeliveriesParams{
+			LeaseUntil: now.Add(webhookLease),
+			Now:        now,
+			Limit:      webhookBatchSize,
+		})
+		if err != nil {
+			return delivered, failed, err
+		}
+
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		for _, d := range batch {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ok := ws.deliver(ctx, d)
+				mu.Lock()
+				defer mu.Unlock()
+				if ok {
+					delivered++
+				} else {
+					failed++
+				}
+			}()
+		}
+		wg.Wait()
+
+		// failed deliveries aren't due again yet, so this ends
+		if len(batch) < webhookBatchSize {
+			return delivered, failed, nil
+		}
+	}
+}
+
+// deliver POSTs one delivery and records the outcome: delivered, pending
+// with a later retry, or dead.
+func (ws *webhookSender) deliver(ctx context.Context, d database.ClaimWebhookDeliveriesRow) bool {
+	now := time.Now()
+	attempt := database.RecordWebhookAttemptParams{
+		ID:            d.ID,
+		Status:        "delivered",
+		AttemptedAt:   now,
+		NextAttemptAt: now,
+	}
+
+	body, err := json.Marshal(webhookPayload{
+		Event:      webhookEvent,
+		DeliveryID: d.ID,
+		WebhookID:  d.WebhookID,
+		Post: apiPostFrom(database.GetPostByIDRow{
+			ID:          d.PostID,
+			CreatedAt:   d.PostCreatedAt,
+			Title:       d.Title,
+			Url:         d.Url,
+			Description: d.Description,
+			PublishedAt: d.PublishedAt,
+			FeedID:      d.FeedID,
+			Content:     d.Content,
+			FeedName:    d.FeedName,
+		}),
+	})
+	if err != nil {
+		// retrying can't help, the payload would fail to encode again
+		attempt.Status = "dead"
+		attempt.LastError = sql.NullString{String: fmt.Sprintf("encode payload: %v", err), Valid: true}
+		log.Printf("webhook delivery %s to %s is dead: encode payload: %v", d.ID, d.WebhookUrl, err)
+		if err := ws.s.db.RecordWebhookAttempt(ctx, attempt); err != nil {
+			log.Printf("error recording webhook delivery %s: %v", d.ID, err)
+		}
+		return false
+	}
+
+	resp, err := ws.post(ctx, d, body)
+	if resp.code != 0 {
+		attempt.LastStatus = sql.NullInt32{Int32: int32(resp.code), Valid: true}
+	}
+
+	if err != nil {
+		attempt.LastError = sql.NullString{String: err.Error(), Valid: true}
+		attempts := int(d.Attempts) + 1
+		switch {
+		case attempts >= webhookMaxAttempts, resp.code == http.StatusGone:
+			attempt.Status = "dead"
+			log.Printf("webhook delivery %s to %s is dead after %d attempts: %v", d.ID, d.WebhookUrl, attempts, err)
+		default:
+			attempt.Status = "pending"
+			attempt.NextAttemptAt = now.Add(max(webhookRetryBase<<(attempts-1), resp.retryAfter))
+			log.Printf("webhook delivery %s to %s failed (attempt %d): %v", d.ID, d.WebhookUrl, attempts, err)
+		}
+	}
+
+	if err := ws.s.db.RecordWebhookAttempt(ctx, attempt); err != nil {
+		log.Printf("error recording webhook delivery %s: %v", d.ID, err)
+	}
+	return attempt.Status == "delivered"
+}
+
+// webhookResponse is what a webhook answered; code is zero if it didn't.
+type webhookResponse struct {
+	code       int
+	retryAfter time.Duration
+}
+
+func (ws *webhookSender) post(ctx context.Context, d database.ClaimWebhookDeliveriesRow, body []byte) (webhookResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.WebhookUrl, bytes.NewReader(body))
+	if err != nil {
+		return webhookResponse{}, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "gator")
+	req.Header.Set("X-Gator-Event", webhookEvent)
+	req.Header.Set("X-Gator-Delivery", d.ID.String())
+	req.Header.Set("X-Gator-Signature", signWebhook(d.Secret, body))
+
+	resp, err := ws.client.Do(req)
+	if err != nil {
+		return webhookResponse{}, fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+	// drain a little so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	r := webhookResponse{code: resp.StatusCode}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		retryAfter, _ := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		r.retryAfter = min(retryAfter, webhookMaxRetryAfter)
+		return r, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return r, nil
+}
+
+func handlerAddWebhook(s *state, cmd command, user database.User) error {
+	fs := newFlagSet("addwebhook")
+	feedURL := fs.String("feed", "", "only send posts from this feed")
+	secret := fs.String("secret", "", "key to sign payloads with (default: a random one)")
+	args, err := parseArgs(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return errors.New("addwebhook requires a url")
+	}
+	target, err := url.Parse(args[0])
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return fmt.Errorf("invalid webhook url: %s", args[0])
+	}
+
+	ctx := context.Background()
+	var feedID uuid.NullUUID
+	if *feedURL != "" {
+		feed, err := s.db.GetFeedByURL(ctx, *feedURL)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("feed not found: %s", *feedURL)
+			}
+			return err
+		}
+		feedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+	}
+	if *secret == "" {
+		if *secret, err = newWebhookSecret(); err != nil {
+			return err
+		}
+	}
+
+	hook, err := s.db.CreateWebhook(ctx, database.CreateWebhookParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UserID:    user.ID,
+		FeedID:    feedID,
+		Url:       target.String(),
+		Secret:    *secret,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("created webhook %s\n", hook.ID)
+	fmt.Printf("  url: %s\n", hook.Url)
+	fmt.Printf("  secret: %s\n", hook.Secret)
+	if !feedID.Valid {
+		fmt.Println("new posts in every feed you follow will be sent")
+	}
+	return nil
+}
+
+func handlerWebhooks(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 0 {
+		return errors.New("webhooks takes no arguments")
+	}
+
+	hooks, err := s.db.GetWebhooksForUser(context.Background(), user.ID)
+	if err != nil {
+		return err
+	}
+
+	l := newListing("id", "url", "feed_id", "feed_name", "pending", "delivered", "dead", "created_at")
+	for _, h := range hooks {
+		l.add(h.ID, h.Url, h.FeedID, h.FeedName, h.Pending, h.Delivered, h.Dead, h.CreatedAt)
+	}
+	return render(s, l, func() {
+		for _, h := range hooks {
+			fmt.Printf("* %s\n", h.Url)
+			fmt.Printf("  id: %s\n", h.ID)
+			if h.FeedName.Valid {
+				fmt.Printf("  feed: %s\n", h.FeedName.String)
+			} else {
+				fmt.Println("  feed: every feed you follow")
+			}
+			fmt.Printf("  deliveries: %d pending, %d delivered, %d dead\n", h.Pending, h.Delivered, h.Dead)
+		}
+	})
+}
+
+func handlerRemoveWebhook(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 1 {
+		return errors.New("removewebhook requires a webhook id")
+	}
+	id, err := uuid.Parse(cmd.args[0])
+	if err != nil {
+		return fmt.Errorf("invalid webhook id: %s", cmd.args[0])
+	}
+
+	n, err := s.db.DeleteWebhook(context.Background(), database.DeleteWebhookParams{
+		ID:     id,
+		UserID: user.ID,
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("webhook not found: %s", id)
+	}
+
+	fmt.Println("webhook removed")
+	return nil
+}
+
+func handlerWebhookDeliveries(s *state, cmd command, user database.User) error {
+	fs := newFlagSet("webhookdeliveries")
+	status := fs.String("status", "", "only show pending, delivered or dead deliveries")
+	limit := fs.Int("limit", 20, "number of deliveries to show")
+	args, err := parseArgs(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return errors.New("webhookdeliveries takes no arguments")
+	}
+	switch *status {
+	case "", "pending", "delivered", "dead":
+	default:
+		return fmt.Errorf("unknown status: %s", *status)
+	}
+	if *limit <= 0 {
+		return errors.New("webhookdeliveries --limit must be a positive integer")
+	}
+
+	deliveries, err := s.db.GetWebhookDeliveriesForUser(context.Background(), database.GetWebhookDeliveriesForUserParams{
+		UserID: user.ID,
+		Status: *status,
+		Limit:  int32(*limit),
+	})
+	if err != nil {
+		return err
+	}
+
+	l := newListing("id", "webhook_id", "webhook_url", "post_title", "status", "attempts",
+		"next_attempt_at", "last_attempt_at", "last_status", "last_error", "created_at")
+	for _, d := range deliveries {
+		l.add(d.ID, d.WebhookID, d.WebhookUrl, d.PostTitle, d.Status, d.Attempts,
+			d.NextAttemptAt, d.LastAttemptAt, d.LastStatus, d.LastError, d.CreatedAt)
+	}
+	return render(s, l, func() {
+		for _, d := range deliveries {
+			fmt.Printf("* %s  %s\n", strings.ToUpper(d.Status), d.PostTitle)
+			fmt.Printf("  id: %s\n", d.ID)
+			fmt.Printf("  webhook: %s\n", d.WebhookUrl)
+			fmt.Printf("  attempts: %d\n", d.Attempts)
+			if d.LastError.Valid {
+				fmt.Printf("  last error: %s\n", d.LastError.String)
+			}
+			if d.Status == "pending" {
+				fmt.Printf("  next attempt: %s\n", fromTimestamp(d.NextAttemptAt).Format("Mon Jan 2 15:04:05"))
+			}
+		}
+	})
+}
+
+func handlerRedeliver(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 0 {
+		return errors.New("redeliver takes no arguments")
+	}
+
+	n, err := s.db.RequeueDeadWebhookDeliveries(context.Background(), database.RequeueDeadWebhookDeliveriesParams{
+		Now:    time.Now(),
+		UserID: user.ID,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("queued %d dead deliveries again\n", n)
+	return nil
+}
+
+// handlerDeliverWebhooks sends the deliveries that are due once, for
+// setups that don't run agg or want to test a webhook right away.
+func handlerDeliverWebhooks(s *state, cmd command) error {
+	if len(cmd.args) != 0 {
+		return errors.New("deliverwebhooks takes no arguments")
+	}
+
+	delivered, failed, err := newWebhookSender(s).deliverDue(context.Background())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("delivered %d, failed %d\n", delivered, failed)
+	return nil
+}
+
+// handlerReceiveWebhooks is a local endpoint to point webhooks at while
+// trying them out: it prints every delivery and checks its signature.
+func handlerReceiveWebhooks(s *state, cmd command) error {
+	fs := newFlagSet("receivewebhooks")
+	addr := fs.String("addr", "localhost:9090", "address to listen on")
+	secret := fs.String("secret", "", "check signatures against this secret")
+	status := fs.Int("status", http.StatusNoContent, "status to answer with, e.g. 500 to test retries")
+	args, err := parseArgs(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return errors.New("receivewebhooks takes no arguments")
+	}
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(io.LimitReader(r.Body, apiMaxBodyBytes))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		signature := "not checked"
+		if *secret != "" {
+			signature = "valid"
+			if !hmac.Equal([]byte(r.Header.Get("X-Gator-Signature")), []byte(signWebhook(*secret, body))) {
+				signature = "INVALID"
+			}
+		}
+		fmt.Printf("%s %s delivery %s (signature %s)\n",
+			time.Now().Format(time.TimeOnly), r.Header.Get("X-Gator-Event"), r.Header.Get("X-Gator-Delivery"), signature)
+		var out bytes.Buffer
+		if json.Indent(&out, body, "  ", "  ") == nil {
+			fmt.Printf("  %s\n", out.String())
+		}
+		w.WriteHeader(*status)
+	}
+
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           http.HandlerFunc(handler),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	fmt.Printf("Receiving webhooks on http://%s, answering %d\n", *addr, *status)
+	return srv.ListenAndServe()
+}
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gator/internal/database"
+	"hash"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// lease we ask hubs for; they are free to grant a different one
+	websubLeaseSeconds = 10 * 24 * 60 * 60
+	// renew leases that expire within this window
+	websubRenewWindow = 24 * time.Hour
+	// re-send a subscription request the hub never verified after this long
+	websubPendingRetry = time.Hour
+
+	maxPushBodySize = 10 << 20
+)
+
+// websubSubscriber subscribes to feeds that advertise a WebSub
+// (PubSubHubbub) hub and ingests the content hubs push to its callback
+// endpoint. Callbacks are <publicURL>/websub/<subscription id>.
+type websubSubscriber struct {
+	s         *state
+	publicURL string
+	client    *http.Client
+}
+
+func newWebSubSubscriber(s *state, publicURL string) *websubSubscriber {
+	return &websubSubscriber{
+		s:         s,
+		publicURL: strings.TrimRight(publicURL, "/"),
+		client:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (ws *websubSubscriber) callbackURL(subID uuid.UUID) string {
+	return ws.publicURL + "/websub/" + subID.String()
+}
+
+// handler serves the callback endpoint hubs talk to.
+func (ws *websubSubscriber) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /websub/{id}", ws.handleVerify)
+	mux.HandleFunc("POST /websub/{id}", ws.handlePush)
+	return mux
+}
+
+// ensureSubscribed makes sure we hold, or have asked for, a lease on the
+// hub advertised by feed. It is called after every successful scrape, so
+// it only talks to the hub when something needs doing.
+func (ws *websubSubscriber) ensureSubscribed(ctx context.Context, feed database.Feed, hub, topic string) error {
+	secret := ""
+	sub, err := ws.s.db.GetWebSubSubscriptionByFeed(ctx, feed.ID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		return err
+	case sub.HubUrl == hub && sub.TopicUrl == topic:
+		switch sub.State {
+		case "denied":
+			return nil
+		case "active":
+			if sub.LeaseExpiresAt.Valid && time.Until(fromTimestamp(sub.LeaseExpiresAt.Time)) > websubRenewWindow {
+				return nil
+			}
+		case "pending":
+			if time.Since(fromTimestamp(sub.UpdatedAt)) < websubPendingRetry {
+				return nil
+			}
+		}
+		secret = sub.Secret
+	}
+
+	if secret == "" {
+		if secret, err = newWebSubSecret(); err != nil {
+			return err
+		}
+	}
+	return ws.subscribe(ctx, feed.ID, hub, topic, secret)
+}
+
+// renew re-subscribes to every active lease that is about to expire.
+func (ws *websubSubscriber) renew(ctx context.Context) {
+	subs, err := ws.s.db.GetWebSubSubscriptionsToRenew(ctx, sql.NullTime{
+		Time:  time.Now().Add(websubRenewWindow),
+		Valid: true,
+	})
+	if err != nil {
+		log.Printf("websub: error listing leases to renew: %v", err)
+		return
+	}
+
+	for _, sub := range subs {
+		if err := ws.subscribe(ctx, sub.FeedID, sub.HubUrl, sub.TopicUrl, sub.Secret); err != nil {
+			log.Printf("websub: error renewing lease (topic=%s): %v", sub.TopicUrl, err)
+		}
+	}
+}
+
+func (ws *websubSubscriber) subscribe(ctx context.Context, feedID uuid.UUID, hub, topic, secret string) error {
+	now := time.Now()
+	sub, err := ws.s.db.UpsertWebSubSubscription(ctx, database.UpsertWebSubSubscriptionParams{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		FeedID:    feedID,
+		HubUrl:    hub,
+		TopicUrl:  topic,
+		Secret:    secret,
+	})
+	if err != nil {
+		return err
+	}
+
+	form := url.Values{
+		"hub.callback":      {ws.callbackURL(sub.ID)},
+		"hub.mode":          {"subscribe"},
+		"hub.topic":         {topic},
+		"hub.secret":        {secret},
+		"hub.lease_seconds": {strconv.Itoa(websubLeaseSeconds)},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hub, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", "gator")
+
+	resp, err := ws.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// hubs answer 202 Accepted and verify the intent asynchronously
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("hub rejected subscription: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	fmt.Printf("websub: requested subscription to %s via %s\n", topic, hub)
+	return nil
+}
+
+// handleVerify answers the hub's verification of intent.
+func (ws *websubSubscriber) handleVerify(w http.ResponseWriter, r *http.Request) {
+	sub, ok := ws.subscription(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	if q.Get("hub.topic") != sub.TopicUrl {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch q.Get("hub.mode") {
+	case "subscribe":
+		lease, err := strconv.Atoi(q.Get("hub.lease_seconds"))
+		if err != nil || lease <= 0 {
+			lease = websubLeaseSeconds
+		}
+		err = ws.s.db.ActivateWebSubSubscription(r.Context(), database.ActivateWebSubSubscriptionParams{
+			ID:             sub.ID,
+			LeaseExpiresAt: sql.NullTime{Time: time.Now().Add(time.Duration(lease) * time.Second), Valid: true},
+		})
+		if err != nil {
+			log.Printf("websub: error activating subscription (topic=%s): %v", sub.TopicUrl, err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		fmt.Printf("websub: subscribed to %s for %ds\n", sub.TopicUrl, lease)
+		io.WriteString(w, q.Get("hub.challenge"))
+
+	case "denied":
+		err := ws.s.db.SetWebSubSubscriptionState(r.Context(), database.SetWebSubSubscriptionStateParams{
+			ID:    sub.ID,
+			State: "denied",
+		})
+		if err != nil {
+			log.Printf("websub: error recording denial (topic=%s): %v", sub.TopicUrl, err)
+		}
+		fmt.Printf("websub: hub denied subscription to %s: %s\n", sub.TopicUrl, q.Get("hub.reason"))
+		w.WriteHeader(http.StatusOK)
+
+	default:
+		// we never unsubscribe, so any other request wasn't ours
+		http.NotFound(w, r)
+	}
+}
+
+// handlePush ingests content distributed by the hub through the same path
+// scrapeFeeds uses.
+func (ws *websubSubscriber) handlePush(w http.ResponseWriter, r *http.Request) {
+	sub, ok := ws.subscription(w, r)
+	if !ok {
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPushBodySize))
+	if err != nil {
+		http.Error(w, "body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	// the spec asks subscribers to acknowledge, but ignore, pushes with a
+	// bad signature so forgers can't tell whether they got it right
+	if !validHubSignature(sub.Secret, r.Header.Get("X-Hub-Signature"), body) {
+		log.Printf("websub: ignoring push with invalid signature (topic=%s)", sub.TopicUrl)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	rss, err := parseFeed(body)
+	if err != nil {
+		log.Printf("websub: error parsing push (topic=%s): %v", sub.TopicUrl, err)
+		http.Error(w, "invalid feed", http.StatusBadRequest)
+		return
+	}
+
+	feed, err := ws.s.db.GetFeedByID(r.Context(), sub.FeedID)
+	if err != nil {
+		log.Printf("websub: error loading feed (topic=%s): %v", sub.TopicUrl, err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	inserted := storePosts(ws.s, feed, rss.Channel.Item)
+	fmt.Printf("websub: %s pushed %d new posts\n", feed.Name, inserted)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (ws *websubSubscriber) subscription(w http.ResponseWriter, r *http.Request) (database.WebsubSubscription, bool) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.NotFound(w, r)
+		return database.WebsubSubscription{}, false
+	}
+
+	sub, err := ws.s.db.GetWebSubSubscription(r.Context(), id)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("websub: error loading subscription %s: %v", id, err)
+		}
+		http.NotFound(w, r)
+		return database.WebsubSubscription{}, false
+	}
+	return sub, true
+}
+
+// validHubSignature checks an X-Hub-Signature header ("sha256=<hex>") against
+// the HMAC of body under secret.
+func validHubSignature(secret, header string, body []byte) bool {
+	method, sig, ok := strings.Cut(header, "=")
+	if !ok {
+		return false
+	}
+
+	var h func() hash.Hash
+	switch strings.ToLower(method) {
+	case "sha1":
+		h = sha1.New
+	case "sha256":
+		h = sha256.New
+	case "sha384":
+		h = sha512.New384
+	case "sha512":
+		h = sha512.New
+	default:
+		return false
+	}
+
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(h, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// websubLinks returns the hub and topic URLs a feed advertises, either as
+// <atom:link> elements or HTTP Link headers. topic is empty when the feed
+// doesn't name itself.
+func websubLinks(feed *RSSFeed) (hub, topic string) {
+	for _, l := range feed.Channel.AtomLinks {
+		switch strings.ToLower(l.Rel) {
+		case "hub":
+			if hub == "" {
+				hub = l.Href
+			}
+		case "self":
+			if topic == "" {
+				topic = l.Href
+			}
+		}
+	}
+
+	for _, header := range feed.LinkHeaders {
+		for _, link := range strings.Split(header, ",") {
+			target, rel, ok := parseLink(link)
+			if !ok {
+				continue
+			}
+			for _, r := range strings.Fields(strings.ToLower(rel)) {
+				if r == "hub" && hub == "" {
+					hub = target
+				}
+				if r == "self" && topic == "" {
+					topic = target
+				}
+			}
+		}
+	}
+
+	return hub, topic
+}
+
+// parseLink parses one `<url>; rel="hub"` entry of a Link header.
+func parseLink(link string) (target, rel string, ok bool) {
+	parts := strings.Split(link, ";")
+	target = strings.TrimSpace(parts[0])
+	if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+		return "", "", false
+	}
+	target = strings.Trim(target, "<>")
+
+	for _, param := range parts[1:] {
+		key, value, found := strings.Cut(strings.TrimSpace(param), "=")
+		if found && strings.EqualFold(key, "rel") {
+			return target, strings.Trim(value, `"`), true
+		}
+	}
+	return "", "", false
+}
+
+func newWebSubSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate secret: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"gator/internal/database"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const websubTestPublicURL = "https://gator.example"
+
+// websubTest is a subscriber whose feed, subscription and posts live in
+// memory.
+type websubTest struct {
+	ws   *websubSubscriber
+	feed database.Feed
+
+	mu        sync.Mutex
+	sub       *database.WebsubSubscription
+	activated []database.ActivateWebSubSubscriptionParams
+	posts     []database.Post
+}
+
+func newWebSubTest(t *testing.T) *websubTest {
+	now := time.Now().UTC()
+	wt := &websubTest{feed: database.Feed{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Name:      "Example",
+		Url:       "https://example.com/feed.xml",
+		UserID:    uuid.New(),
+	}}
+
+	db := newFakeDB()
+	db.on("GetWebSubSubscriptionByFeed", wt.getSubscriptionBy(func(s *database.WebsubSubscription) uuid.UUID { return s.FeedID }))
+	db.on("GetWebSubSubscription", wt.getSubscriptionBy(func(s *database.WebsubSubscription) uuid.UUID { return s.ID }))
+	db.on("UpsertWebSubSubscription", wt.upsertSubscription)
+	db.on("ActivateWebSubSubscription", wt.activateSubscription)
+	db.on("GetFeedByID", func([]driver.Value) ([][]driver.Value, error) {
+		return [][]driver.Value{feedRow(wt.feed)}, nil
+	})
+	db.on("GetPrunedURLs", func([]driver.Value) ([][]driver.Value, error) { return nil, nil })
+	db.on("CreatePost", wt.createPost)
+	db.on("EnqueueWebhookDeliveries", func([]driver.Value) ([][]driver.Value, error) { return nil, nil })
+	db.on("GetAlertRulesForFeed", func([]driver.Value) ([][]driver.Value, error) { return nil, nil })
+
+	wt.ws = newWebSubSubscriber(db.state(t), websubTestPublicURL)
+	return wt
+}
+
+// subscribed stores an active subscription to the test feed, signed with
+// secret.
+func (wt *websubTest) subscribed(secret string) database.WebsubSubscription {
+	wt.mu.Lock()
+	defer wt.mu.Unlock()
+	now := time.Now().UTC()
+	wt.sub = &database.WebsubSubscription{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		FeedID:    wt.feed.ID,
+		HubUrl:    "https://hub.example/",
+		TopicUrl:  wt.feed.Url,
+		Secret:    secret,
+		State:     "active",
+	}
+	return *wt.sub
+}
+
+// getSubscriptionBy answers a query for the subscription whose key is the
+// first argument.
+func (wt *websubTest) getSubscriptionBy(key func(*database.WebsubSubscription) uuid.UUID) fakeQuery {
+	return func(args []driver.Value) ([][]driver.Value, error) {
+		wt.mu.Lock()
+		defer wt.mu.Unlock()
+		if wt.sub == nil || key(wt.sub).String() != args[0] {
+			return nil, nil
+		}
+		return [][]driver.Value{subscriptionRow(*wt.sub)}, nil
+	}
+}
+
+func subscriptionRow(s database.WebsubSubscription) []driver.Value {
+	return fakeRow(s.ID, s.CreatedAt, s.UpdatedAt, s.FeedID,
+		s.HubUrl, s.TopicUrl, s.Secret, s.State, s.LeaseExpiresAt)
+}
+
+func (wt *websubTest) upsertSubscription(args []driver.Value) ([][]driver.Value, error) {
+	wt.mu.Lock()
+	defer wt.mu.Unlock()
+	wt.sub = &database.WebsubSubscription{
+		ID:        uuid.MustParse(args[0].(string)),
+		CreatedAt: args[1].(time.Time),
+		UpdatedAt: args[2].(time.Time),
+		FeedID:    uuid.MustParse(args[3].(string)),
+		HubUrl:    args[4].(string),
+		TopicUrl:  args[5].(string),
+		Secret:    args[6].(string),
+		State:     "pending",
+	}
+	return [][]driver.Value{subscriptionRow(*wt.sub)}, nil
+}
+
+func (wt *websubTest) activateSubscription(args []driver.Value) ([][]driver.Value, error) {
+	wt.mu.Lock()
+	defer wt.mu.Unlock()
+	p := database.ActivateWebSubSubscriptionParams{ID: uuid.MustParse(args[0].(string))}
+	if t, ok := args[1].(time.Time); ok {
+		p.LeaseExpiresAt.Time, p.LeaseExpiresAt.Valid = t, true
+	}
+	wt.activated = append(wt.activated, p)
+	return [][]driver.Value{nil}, nil
+}
+
+func (wt *websubTest) createPost(args []driver.Value) ([][]driver.Value, error) {
+	wt.mu.Lock()
+	defer wt.mu.Unlock()
+	p := database.Post{
+		ID:        uuid.MustParse(args[0].(string)),
+		CreatedAt: args[1].(time.Time),
+		UpdatedAt: args[2].(time.Time),
+		Title:     args[3].(string),
+		Url:       args[4].(string),
+		FeedID:    uuid.MustParse(args[7].(string)),
+	}
+	wt.posts = append(wt.posts, p)
+	return [][]driver.Value{fakeRow(p.ID, p.CreatedAt, p.UpdatedAt, p.Title, p.Url,
+		p.Description, p.PublishedAt, p.FeedID, p.Content, nil, p.RawDescription,
+		p.RawContent, p.Author, pq.Array(p.Categories), true)}, nil
+}
+
+// serve sends a request to the subscriber's callback endpoint.
+func (wt *websubTest) serve(r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	wt.ws.handler().ServeHTTP(w, r)
+	return w
+}
+
+func TestWebSubSubscribe(t *testing.T) {
+	wt := newWebSubTest(t)
+
+	var got url.Values
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("hub: %v", err)
+		}
+		got = r.PostForm
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer hub.Close()
+
+	if err := wt.ws.ensureSubscribed(context.Background(), wt.feed, hub.URL, wt.feed.Url); err != nil {
+		t.Fatal(err)
+	}
+
+	if wt.sub == nil || wt.sub.State != "pending" {
+		t.Fatalf("subscription %+v, want a pending one", wt.sub)
+	}
+	want := url.Values{
+		"hub.callback":      {websubTestPublicURL + "/websub/" + wt.sub.ID.String()},
+		"hub.mode":          {"subscribe"},
+		"hub.topic":         {wt.feed.Url},
+		"hub.secret":        {wt.sub.Secret},
+		"hub.lease_seconds": {"864000"},
+	}
+	if got.Encode() != want.Encode() {
+		t.Errorf("hub got\n%s\nwant\n%s", got.Encode(), want.Encode())
+	}
+	if len(wt.sub.Secret) != 64 {
+		t.Errorf("secret %q, want 32 random bytes in hex", wt.sub.Secret)
+	}
+}
+
+func TestWebSubSubscribeRejected(t *testing.T) {
+	wt := newWebSubTest(t)
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unknown topic", http.StatusBadRequest)
+	}))
+	defer hub.Close()
+
+	err := wt.ws.ensureSubscribed(context.Background(), wt.feed, hub.URL, wt.feed.Url)
+	if err == nil || !strings.Contains(err.Error(), "unknown topic") {
+		t.Fatalf("got error %v, want the hub's rejection", err)
+	}
+}
+
+func TestWebSubVerify(t *testing.T) {
+	wt := newWebSubTest(t)
+	sub := wt.subscribed("secret")
+
+	verify := func(id, topic string) *httptest.ResponseRecorder {
+		q := url.Values{
+			"hub.mode":          {"subscribe"},
+			"hub.topic":         {topic},
+			"hub.challenge":     {"challenge-1234"},
+			"hub.lease_seconds": {"3600"},
+		}
+		return wt.serve(httptest.NewRequest("GET", "/websub/"+id+"?"+q.Encode(), nil))
+	}
+
+	if w := verify(sub.ID.String(), "https://other.example/feed.xml"); w.Code != http.StatusNotFound {
+		t.Errorf("wrong topic: got status %d, want 404", w.Code)
+	}
+	if w := verify(uuid.NewString(), sub.TopicUrl); w.Code != http.StatusNotFound {
+		t.Errorf("unknown subscription: got status %d, want 404", w.Code)
+	}
+	if len(wt.activated) != 0 {
+		t.Fatalf("activated %+v after bad verifications", wt.activated)
+	}
+
+	w := verify(sub.ID.String(), sub.TopicUrl)
+	if w.Code != http.StatusOK || w.Body.String() != "challenge-1234" {
+		t.Fatalf("got %d %q, want the challenge echoed", w.Code, w.Body.String())
+	}
+	if len(wt.activated) != 1 || wt.activated[0].ID != sub.ID {
+		t.Fatalf("activated %+v, want subscription %s", wt.activated, sub.ID)
+	}
+	lease := time.Until(wt.activated[0].LeaseExpiresAt.Time)
+	if lease < 59*time.Minute || lease > time.Hour {
+		t.Errorf("lease expires in %s, want the hub's hour", lease)
+	}
+}
+
+const websubTestPush = `<?xml version="1.0"?>
+<rss version="2.0">
+<channel>
+  <title>Example</title>
+  <link>https://example.com/</link>
+  <item>
+    <title>First</title>
+    <link>https://example.com/first</link>
+    <pubDate>Mon, 06 May 2024 10:00:00 +0000</pubDate>
+  </item>
+  <item>
+    <title>Second</title>
+    <link>https://example.com/second</link>
+  </item>
+</channel>
+</rss>`
+
+func hubSignature(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestWebSubPush(t *testing.T) {
+	tests := []struct {
+		name      string
+		signature string
+		want      int
+		wantPosts []string
+	}{
+		{"valid", hubSignature("secret", websubTestPush), http.StatusNoContent, []string{"First", "Second"}},
+		{"missing", "", http.StatusAccepted, nil},
+		{"wrong secret", hubSignature("guess", websubTestPush), http.StatusAccepted, nil},
+		{"other body", hubSignature("secret", "<rss/>"), http.StatusAccepted, nil},
+		{"not hex", "sha256=zz", http.StatusAccepted, nil},
+		{"unknown method", "md5=" + hubSignature("secret", websubTestPush)[len("sha256="):], http.StatusAccepted, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wt := newWebSubTest(t)
+			sub := wt.subscribed("secret")
+
+			r := httptest.NewRequest("POST", "/websub/"+sub.ID.String(), strings.NewReader(websubTestPush))
+			r.Header.Set("Content-Type", "application/rss+xml")
+			if tt.signature != "" {
+				r.Header.Set("X-Hub-Signature", tt.signature)
+			}
+			if w := wt.serve(r); w.Code != tt.want {
+				t.Fatalf("got status %d, want %d", w.Code, tt.want)
+			}
+
+			var titles []string
+			for _, p := range wt.posts {
+				if p.FeedID != wt.feed.ID {
+					t.Errorf("post %q stored for feed %s, want %s", p.Title, p.FeedID, wt.feed.ID)
+				}
+				titles = append(titles, p.Title)
+			}
+			if strings.Join(titles, ",") != strings.Join(tt.wantPosts, ",") {
+				t.Errorf("stored %v, want %v", titles, tt.wantPosts)
+			}
+		})
+	}
+}